@@ -0,0 +1,96 @@
+package localstack
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Builder configures a stack by method chaining instead of options, e.g.
+//
+//	stack, err := localstack.NewBuilder().Services("sqs", "s3").Region("eu-west-1").Build()
+//
+// Every method applies the option of the same name, errors of validating options are collected
+// and returned by Build
+type Builder struct {
+	opts []StackOption
+	errs []error
+}
+
+// NewBuilder returns a builder for a stack with the defaults of New
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// Option adds any option, e.g. one the builder has no method for
+func (b *Builder) Option(opt StackOption) *Builder {
+	b.opts = append(b.opts, opt)
+	return b
+}
+
+// OptionErr adds an option that is created with a validation error, like WithServices
+func (b *Builder) OptionErr(opt StackOption, err error) *Builder {
+	if err != nil {
+		b.errs = append(b.errs, err)
+		return b
+	}
+	return b.Option(opt)
+}
+
+// Image applies WithImage
+func (b *Builder) Image(image string) *Builder {
+	return b.Option(WithImage(image))
+}
+
+// Services applies WithServices
+func (b *Builder) Services(services ...string) *Builder {
+	return b.OptionErr(WithServices(services...))
+}
+
+// Region applies WithRegion
+func (b *Builder) Region(region string) *Builder {
+	return b.Option(WithRegion(region))
+}
+
+// Env applies WithEnv
+func (b *Builder) Env(key, value string) *Builder {
+	return b.Option(WithEnv(key, value))
+}
+
+// Context applies WithContext
+func (b *Builder) Context(ctx context.Context) *Builder {
+	return b.Option(WithContext(ctx))
+}
+
+// InitTimeout applies WithInitTimeout
+func (b *Builder) InitTimeout(timeout time.Duration) *Builder {
+	return b.Option(WithInitTimeout(timeout))
+}
+
+// AutoPort applies WithAutoPort
+func (b *Builder) AutoPort() *Builder {
+	return b.Option(WithAutoPort())
+}
+
+// ReuseExisting applies WithReuseExisting
+func (b *Builder) ReuseExisting() *Builder {
+	return b.Option(WithReuseExisting())
+}
+
+// TLS applies WithTLS
+func (b *Builder) TLS() *Builder {
+	return b.Option(WithTLS())
+}
+
+// Build returns a new, unstarted stack with all options applied in the order they were added.
+// Like options passed to Start, they are dropped when Start restarts the stack with forceRestart
+func (b *Builder) Build() (*Stack, error) {
+	if err := errors.Join(b.errs...); err != nil {
+		return nil, err
+	}
+	s := New()
+	for _, opt := range b.opts {
+		opt(s)
+	}
+	return s, nil
+}
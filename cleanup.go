@@ -0,0 +1,106 @@
+package localstack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/aws/smithy-go"
+)
+
+// PurgeQueue deletes all messages from a queue. A missing queue is not an error, and neither is
+// a purge already in progress, SQS only allows one per minute
+func (s *Stack) PurgeQueue(ctx context.Context, queueURL string) error {
+	cfg, err := s.createTestConfig()
+	if err != nil {
+		return err
+	}
+	_, err = sqs.NewFromConfig(cfg).PurgeQueue(ctx, &sqs.PurgeQueueInput{QueueUrl: aws.String(queueURL)})
+	if err != nil && !purgeDone(err) {
+		return fmt.Errorf("localstack: purging queue %s: %w", queueURL, err)
+	}
+	return nil
+}
+
+// purgeDone reports whether a failed purge still leaves the queue empty or gone
+func purgeDone(err error) bool {
+	var inProgress *sqstypes.PurgeQueueInProgress
+	return isNotFound(err) || errors.As(err, &inProgress)
+}
+
+// EmptyBucket deletes every object, including all versions and delete markers, from a bucket. A
+// missing bucket is not an error
+func (s *Stack) EmptyBucket(ctx context.Context, bucket string) error {
+	cfg, err := s.createTestConfig()
+	if err != nil {
+		return err
+	}
+	api := s3.NewFromConfig(cfg, func(o *s3.Options) { o.UsePathStyle = true })
+
+	input := &s3.ListObjectVersionsInput{Bucket: aws.String(bucket)}
+	for {
+		page, err := api.ListObjectVersions(ctx, input)
+		if err != nil {
+			if isNotFound(err) {
+				return nil
+			}
+			return fmt.Errorf("localstack: listing objects of %s: %w", bucket, err)
+		}
+
+		var objects []s3types.ObjectIdentifier
+		for _, version := range page.Versions {
+			objects = append(objects, s3types.ObjectIdentifier{Key: version.Key, VersionId: version.VersionId})
+		}
+		for _, marker := range page.DeleteMarkers {
+			objects = append(objects, s3types.ObjectIdentifier{Key: marker.Key, VersionId: marker.VersionId})
+		}
+		if len(objects) > 0 {
+			if _, err := api.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+				Bucket: aws.String(bucket),
+				Delete: &s3types.Delete{Objects: objects, Quiet: aws.Bool(true)},
+			}); err != nil {
+				return fmt.Errorf("localstack: deleting objects of %s: %w", bucket, err)
+			}
+		}
+
+		if !aws.ToBool(page.IsTruncated) {
+			return nil
+		}
+		input.KeyMarker = page.NextKeyMarker
+		input.VersionIdMarker = page.NextVersionIdMarker
+	}
+}
+
+// DeleteBucket empties and deletes a bucket, a missing bucket is not an error
+func (s *Stack) DeleteBucket(ctx context.Context, bucket string) error {
+	if err := s.EmptyBucket(ctx, bucket); err != nil {
+		return err
+	}
+	cfg, err := s.createTestConfig()
+	if err != nil {
+		return err
+	}
+	api := s3.NewFromConfig(cfg, func(o *s3.Options) { o.UsePathStyle = true })
+	if _, err := api.DeleteBucket(ctx, &s3.DeleteBucketInput{Bucket: aws.String(bucket)}); err != nil && !isNotFound(err) {
+		return fmt.Errorf("localstack: deleting bucket %s: %w", bucket, err)
+	}
+	return nil
+}
+
+// isNotFound reports whether err means the addressed resource does not exist
+func isNotFound(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.ErrorCode() {
+	case "NoSuchBucket", "NoSuchKey", "NotFound", "QueueDoesNotExist", "AWS.SimpleQueueService.NonExistentQueue", "ResourceNotFoundException":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+package localstack
+
+import (
+	"errors"
+	"testing"
+
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PurgeDone(t *testing.T) {
+
+	tests := []struct {
+		name string
+		err  error
+		done bool
+	}{
+		{"queue not found", &sqstypes.QueueDoesNotExist{}, true},
+		{"purge in progress", &sqstypes.PurgeQueueInProgress{}, true},
+		{"other api error", &smithy.GenericAPIError{Code: "AccessDenied"}, false},
+		{"transport error", errors.New("connection refused"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.done, purgeDone(tt.err))
+		})
+	}
+}
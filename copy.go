@@ -0,0 +1,159 @@
+package localstack
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+)
+
+// CopyToContainer copies the file or directory hostPath into the running container, where it is
+// created as containerPath. The parent directory of containerPath has to exist
+func (s *Stack) CopyToContainer(ctx context.Context, hostPath, containerPath string) error {
+	s.RLock()
+	defer s.RUnlock()
+	if !s.started || s.containerID == "" {
+		return ErrNotStarted
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		_ = pw.CloseWithError(writeTar(pw, hostPath, path.Base(containerPath)))
+	}()
+	defer func() { _ = pr.Close() }()
+
+	err := s.cli.CopyToContainer(ctx, s.containerID, path.Dir(containerPath), pr, types.CopyToContainerOptions{})
+	if err != nil {
+		return fmt.Errorf("localstack: copying %s to %s: %w", hostPath, containerPath, err)
+	}
+	return nil
+}
+
+// CopyFromContainer copies the file or directory containerPath out of the running container,
+// where it is created as hostPath
+func (s *Stack) CopyFromContainer(ctx context.Context, containerPath, hostPath string) error {
+	s.RLock()
+	defer s.RUnlock()
+	if !s.started || s.containerID == "" {
+		return ErrNotStarted
+	}
+
+	reader, _, err := s.cli.CopyFromContainer(ctx, s.containerID, containerPath)
+	if err != nil {
+		return fmt.Errorf("localstack: copying %s from the container: %w", containerPath, err)
+	}
+	defer func() { _ = reader.Close() }()
+
+	if err := readTar(reader, hostPath); err != nil {
+		return fmt.Errorf("localstack: copying %s to %s: %w", containerPath, hostPath, err)
+	}
+	return nil
+}
+
+// writeTar writes hostPath to w as a tar archive whose root entry is named name
+func writeTar(w io.Writer, hostPath, name string) error {
+	tw := tar.NewWriter(w)
+	err := filepath.WalkDir(hostPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() && !info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(hostPath, p)
+		if err != nil {
+			return err
+		}
+
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = path.Join(name, filepath.ToSlash(rel))
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = f.Close() }()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// readTar extracts a tar archive as returned by the Docker copy API, replacing the name of its
+// root entry with hostPath
+func readTar(r io.Reader, hostPath string) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := path.Clean(header.Name)
+		rest := ""
+		if i := strings.Index(name, "/"); i >= 0 {
+			rest = name[i+1:]
+		}
+		if rest == ".." || strings.HasPrefix(rest, "../") {
+			return fmt.Errorf("invalid archive entry %q", header.Name)
+		}
+		target := filepath.Join(hostPath, filepath.FromSlash(rest))
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			if err := writeFile(target, tr, os.FileMode(header.Mode).Perm()); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// writeFile writes the content of r to a new file at target
+func writeFile(target string, r io.Reader, perm os.FileMode) error {
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
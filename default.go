@@ -0,0 +1,41 @@
+package localstack
+
+import "sync"
+
+var (
+	defaultOnce  sync.Once
+	defaultStack *Stack
+)
+
+// Default returns the package-level stack shared by every caller, created on first use but not
+// started. Use New for an isolated stack
+func Default() *Stack {
+	defaultOnce.Do(func() {
+		defaultStack = New()
+	})
+	return defaultStack
+}
+
+// MustEndpoint starts the Default stack if it is not running yet and returns its endpoint URL. It
+// panics if the stack cannot be started. Nothing stops the Default stack on its own, call
+// StopDefault from TestMain or the container outlives the test process
+func MustEndpoint() string {
+	stack := Default()
+	if err := stack.Start(false); err != nil {
+		panic(err)
+	}
+	return stack.EndpointURL()
+}
+
+// StopDefault stops the Default stack if it was started, e.g. at the end of TestMain:
+//
+//	func TestMain(m *testing.M) {
+//		code := m.Run()
+//		if err := localstack.StopDefault(); err != nil {
+//			log.Print(err)
+//		}
+//		os.Exit(code)
+//	}
+func StopDefault() error {
+	return Default().Stop()
+}
@@ -0,0 +1,29 @@
+package localstack
+
+import (
+	"reflect"
+)
+
+// WithStackEndpoint returns a client option that points any service client at the stack, for
+// configs that were not built by Config:
+//
+//	api := s3.NewFromConfig(cfg, localstack.WithStackEndpoint[s3.Options](stack))
+//
+// It sets the BaseEndpoint of the client options and, for S3, path-style addressing, which the
+// edge needs as bucket subdomains do not resolve. The stack has to be started when the client is
+// created
+func WithStackEndpoint[O any](s *Stack) func(*O) {
+	return func(o *O) {
+		v := reflect.ValueOf(o).Elem()
+		if v.Kind() != reflect.Struct {
+			return
+		}
+		if field := v.FieldByName("BaseEndpoint"); field.IsValid() && field.CanSet() && field.Type() == reflect.TypeOf((*string)(nil)) {
+			endpoint := s.EndpointURL()
+			field.Set(reflect.ValueOf(&endpoint))
+		}
+		if field := v.FieldByName("UsePathStyle"); field.IsValid() && field.CanSet() && field.Kind() == reflect.Bool {
+			field.SetBool(true)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package localstack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// EnsureQueue creates the queue name unless it exists and returns its URL, reachable through
+// EndpointURL. An existing queue keeps its attributes
+func (s *Stack) EnsureQueue(ctx context.Context, name string) (string, error) {
+	cfg, err := s.createTestConfig()
+	if err != nil {
+		return "", err
+	}
+	api := sqs.NewFromConfig(cfg)
+
+	out, err := api.CreateQueue(ctx, &sqs.CreateQueueInput{QueueName: aws.String(name)})
+	if err == nil {
+		return s.rewriteURL(aws.ToString(out.QueueUrl)), nil
+	}
+	var exists *sqstypes.QueueNameExists
+	if !errors.As(err, &exists) {
+		return "", fmt.Errorf("localstack: creating queue %s: %w", name, err)
+	}
+	existing, err := api.GetQueueUrl(ctx, &sqs.GetQueueUrlInput{QueueName: aws.String(name)})
+	if err != nil {
+		return "", fmt.Errorf("localstack: getting url of queue %s: %w", name, err)
+	}
+	return s.rewriteURL(aws.ToString(existing.QueueUrl)), nil
+}
+
+// EnsureBucket creates the bucket name unless it exists and returns its name
+func (s *Stack) EnsureBucket(ctx context.Context, name string) (string, error) {
+	cfg, err := s.createTestConfig()
+	if err != nil {
+		return "", err
+	}
+	api := s3.NewFromConfig(cfg, func(o *s3.Options) { o.UsePathStyle = true })
+
+	if _, err := api.CreateBucket(ctx, s.createBucketInput(name)); err != nil {
+		var owned *s3types.BucketAlreadyOwnedByYou
+		var exists *s3types.BucketAlreadyExists
+		if !errors.As(err, &owned) && !errors.As(err, &exists) {
+			return "", fmt.Errorf("localstack: creating bucket %s: %w", name, err)
+		}
+	}
+	return name, nil
+}
+
+// createBucketInput creates the bucket in the region of the stack. S3 rejects a location
+// constraint of us-east-1, its default, and requires one for every other region
+func (s *Stack) createBucketInput(name string) *s3.CreateBucketInput {
+	input := &s3.CreateBucketInput{Bucket: aws.String(name)}
+	if s.region != "" && s.region != "us-east-1" {
+		input.CreateBucketConfiguration = &s3types.CreateBucketConfiguration{
+			LocationConstraint: s3types.BucketLocationConstraint(s.region),
+		}
+	}
+	return input
+}
+
+// EnsureTable creates the table described by input unless a table of that name exists and
+// returns its ARN. An existing table is left as it is, even if its schema differs from input
+func (s *Stack) EnsureTable(ctx context.Context, input *dynamodb.CreateTableInput) (string, error) {
+	cfg, err := s.createTestConfig()
+	if err != nil {
+		return "", err
+	}
+	api := dynamodb.NewFromConfig(cfg)
+	name := aws.ToString(input.TableName)
+
+	out, err := api.CreateTable(ctx, input)
+	if err == nil {
+		return aws.ToString(out.TableDescription.TableArn), nil
+	}
+	var inUse *dynamodbtypes.ResourceInUseException
+	if !errors.As(err, &inUse) {
+		return "", fmt.Errorf("localstack: creating table %s: %w", name, err)
+	}
+	existing, err := api.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: input.TableName})
+	if err != nil {
+		return "", fmt.Errorf("localstack: describing table %s: %w", name, err)
+	}
+	return aws.ToString(existing.Table.TableArn), nil
+}
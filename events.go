@@ -0,0 +1,107 @@
+package localstack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	ebtypes "github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// CreateEventRuleToQueue routes the events on bus that match rulePattern to the queue queueName.
+// It creates the bus unless it is empty or "default", the queue, a rule named after the queue
+// and the target, and allows the rule to send to the queue. The returned queue URL is reachable
+// through EndpointURL
+func (s *Stack) CreateEventRuleToQueue(ctx context.Context, bus, rulePattern, queueName string) (ruleArn, queueURL string, err error) {
+	cfg, err := s.createTestConfig()
+	if err != nil {
+		return "", "", err
+	}
+	events := eventbridge.NewFromConfig(cfg)
+	queues := sqs.NewFromConfig(cfg)
+
+	if bus == "" {
+		bus = "default"
+	}
+	if bus != "default" {
+		if _, err := events.CreateEventBus(ctx, &eventbridge.CreateEventBusInput{Name: aws.String(bus)}); err != nil {
+			var exists *ebtypes.ResourceAlreadyExistsException
+			if !errors.As(err, &exists) {
+				return "", "", fmt.Errorf("localstack: creating event bus %s: %w", bus, err)
+			}
+		}
+	}
+
+	queue, err := queues.CreateQueue(ctx, &sqs.CreateQueueInput{QueueName: aws.String(queueName)})
+	if err != nil {
+		return "", "", fmt.Errorf("localstack: creating queue %s: %w", queueName, err)
+	}
+	attrs, err := queues.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl:       queue.QueueUrl,
+		AttributeNames: []sqstypes.QueueAttributeName{sqstypes.QueueAttributeNameQueueArn},
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("localstack: reading arn of queue %s: %w", queueName, err)
+	}
+	queueArn := attrs.Attributes[string(sqstypes.QueueAttributeNameQueueArn)]
+
+	ruleName := queueName + "-rule"
+	rule, err := events.PutRule(ctx, &eventbridge.PutRuleInput{
+		Name:         aws.String(ruleName),
+		EventBusName: aws.String(bus),
+		EventPattern: aws.String(rulePattern),
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("localstack: creating rule %s: %w", ruleName, err)
+	}
+	ruleArn = aws.ToString(rule.RuleArn)
+
+	policy, err := queueSendPolicy(queueArn, ruleArn)
+	if err != nil {
+		return "", "", err
+	}
+	if _, err := queues.SetQueueAttributes(ctx, &sqs.SetQueueAttributesInput{
+		QueueUrl:   queue.QueueUrl,
+		Attributes: map[string]string{string(sqstypes.QueueAttributeNamePolicy): policy},
+	}); err != nil {
+		return "", "", fmt.Errorf("localstack: setting policy of queue %s: %w", queueName, err)
+	}
+
+	targets, err := events.PutTargets(ctx, &eventbridge.PutTargetsInput{
+		Rule:         aws.String(ruleName),
+		EventBusName: aws.String(bus),
+		Targets:      []ebtypes.Target{{Id: aws.String(queueName), Arn: aws.String(queueArn)}},
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("localstack: adding queue %s to rule %s: %w", queueName, ruleName, err)
+	}
+	if targets.FailedEntryCount > 0 {
+		entry := targets.FailedEntries[0]
+		return "", "", fmt.Errorf("localstack: adding queue %s to rule %s: %s: %s", queueName, ruleName, aws.ToString(entry.ErrorCode), aws.ToString(entry.ErrorMessage))
+	}
+	return ruleArn, s.rewriteURL(aws.ToString(queue.QueueUrl)), nil
+}
+
+// queueSendPolicy allows the rule ruleArn to send messages to the queue queueArn
+func queueSendPolicy(queueArn, ruleArn string) (string, error) {
+	policy := map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []map[string]interface{}{{
+			"Effect":    "Allow",
+			"Principal": map[string]string{"Service": "events.amazonaws.com"},
+			"Action":    "sqs:SendMessage",
+			"Resource":  queueArn,
+			"Condition": map[string]interface{}{"ArnEquals": map[string]string{"aws:SourceArn": ruleArn}},
+		}},
+	}
+	raw, err := json.Marshal(policy)
+	if err != nil {
+		return "", fmt.Errorf("localstack: encoding queue policy: %w", err)
+	}
+	return string(raw), nil
+}
@@ -0,0 +1,179 @@
+package localstack
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+)
+
+// Health is the state reported by the LocalStack health endpoint
+type Health struct {
+	Services map[string]string `json:"services"`
+	Edition  string            `json:"edition"`
+	Version  string            `json:"version"`
+}
+
+// Health fetches the current state from the LocalStack health endpoint
+func (s *Stack) Health(ctx context.Context) (Health, error) {
+	var health Health
+	if s.EndpointURL() == "" {
+		return health, ErrNotStarted
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.EndpointURL()+"/_localstack/health", nil)
+	if err != nil {
+		return health, err
+	}
+	resp, err := s.httpClient().Do(req)
+	if err != nil {
+		return health, fmt.Errorf("localstack: health check: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return health, fmt.Errorf("localstack: health check: unexpected status %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		return health, fmt.Errorf("localstack: health check: %w", err)
+	}
+	return health, nil
+}
+
+// ErrProRequired is returned by RequirePro when the stack runs the community edition
+var ErrProRequired = errors.New("localstack: requires LocalStack Pro")
+
+// Edition returns the edition the health endpoint reports, e.g. "community" or "pro". It is
+// fetched once per run of the stack
+func (s *Stack) Edition(ctx context.Context) (string, error) {
+	s.cfgMu.Lock()
+	edition := s.edition
+	s.cfgMu.Unlock()
+	if edition != "" {
+		return edition, nil
+	}
+
+	health, err := s.Health(ctx)
+	if err != nil {
+		return "", err
+	}
+	if health.Edition == "" {
+		return "", fmt.Errorf("localstack: health endpoint does not report an edition")
+	}
+	s.cfgMu.Lock()
+	s.edition = health.Edition
+	s.cfgMu.Unlock()
+	return health.Edition, nil
+}
+
+// RequirePro fails with ErrProRequired unless the stack runs a Pro edition, e.g. to skip a test of
+// RDS or IAM enforcement instead of failing it with a "not implemented" error of the community
+// edition
+func (s *Stack) RequirePro(ctx context.Context) error {
+	edition, err := s.Edition(ctx)
+	if err != nil {
+		return err
+	}
+	if edition == "community" {
+		return fmt.Errorf("%w, the stack runs the %s edition", ErrProRequired, edition)
+	}
+	return nil
+}
+
+// WatchHealth polls the health endpoint, backing off from the ready poll interval, and sends every
+// distinct state on the returned channel, which is closed once ctx is done or the stack is stopped.
+// It needs a started stack and Start only returns once LocalStack is ready, so to watch the
+// startup transitions start with WithNotInitWait, then watch and call WaitReady
+func (s *Stack) WatchHealth(ctx context.Context) (<-chan Health, error) {
+	s.RLock()
+	started, stopped, interval := s.started, s.stopped, s.readyPollInterval
+	s.RUnlock()
+	if !started {
+		return nil, ErrNotStarted
+	}
+
+	ch := make(chan Health)
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-stopped:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	go func() {
+		defer close(ch)
+		defer cancel()
+		b := newBackoff(interval)
+
+		var last *Health
+		for {
+			if health, err := s.Health(ctx); err == nil && (last == nil || !reflect.DeepEqual(*last, health)) {
+				last = &health
+				select {
+				case ch <- health:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if b.wait(ctx) != nil {
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// healthReady reports whether the health endpoint answers with every service usable, apart from
+// the ones ignored with WithIgnoreServicesInReadiness
+func (s *Stack) healthReady(ctx context.Context) bool {
+	health, err := s.Health(ctx)
+	if err != nil {
+		return false
+	}
+	for service, status := range health.Services {
+		if s.readinessIgnored[service] {
+			continue
+		}
+		switch status {
+		case "available", "running", "disabled":
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// httpClient returns the client for raw HTTP calls to the edge. It is built once per run, so that
+// polling reuses its keep-alive connections
+func (s *Stack) httpClient() *http.Client {
+	s.cfgMu.Lock()
+	defer s.cfgMu.Unlock()
+	if s.rawClient == nil {
+		tr := http.DefaultTransport.(*http.Transport).Clone()
+		s.configureTransport(tr)
+		s.rawClient = &http.Client{Transport: tr}
+	}
+	return s.rawClient
+}
+
+// configureTransport prepares a transport for talking to the edge, shared by the SDK clients and
+// the raw HTTP calls of the stack
+func (s *Stack) configureTransport(tr *http.Transport) {
+	tr.Proxy = s.edgeProxy
+	if s.useTLS {
+		// LocalStack serves a self-signed certificate, so verification is skipped
+		tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+}
+
+// edgeProxy honors the proxy environment (HTTP_PROXY, NO_PROXY, ...) but never proxies requests to
+// the edge itself, which a corporate proxy cannot reach when it only listens on the test host
+func (s *Stack) edgeProxy(req *http.Request) (*url.URL, error) {
+	if endpoint, err := url.Parse(s.EndpointURL()); err == nil && endpoint.Host != "" && endpoint.Host == req.URL.Host {
+		return nil, nil
+	}
+	return http.ProxyFromEnvironment(req)
+}
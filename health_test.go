@@ -0,0 +1,194 @@
+package localstack
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WatchHealth(t *testing.T) {
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/_localstack/health", r.URL.Path)
+		status := "initializing"
+		if atomic.AddInt32(&calls, 1) > 2 {
+			status = "available"
+		}
+		_, _ = fmt.Fprintf(w, `{"services": {"sqs": %q}, "edition": "community"}`, status)
+	}))
+	defer server.Close()
+
+	stack := Attach(server.URL)
+	stack.readyPollInterval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := stack.WatchHealth(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, "initializing", (<-ch).Services["sqs"])
+	assert.Equal(t, "available", (<-ch).Services["sqs"])
+
+	cancel()
+	for range ch {
+	}
+}
+
+func Test_WaitReadyAttached(t *testing.T) {
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := "initializing"
+		if atomic.AddInt32(&calls, 1) > 2 {
+			status = "running"
+		}
+		_, _ = fmt.Fprintf(w, `{"services": {"sqs": %q, "cloudwatch": "error"}}`, status)
+	}))
+	defer server.Close()
+
+	stack := Attach(server.URL)
+	stack.readyPollInterval = 10 * time.Millisecond
+	WithIgnoreServicesInReadiness("cloudwatch")(stack)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, stack.WaitReady(ctx))
+	assert.Greater(t, atomic.LoadInt32(&calls), int32(2))
+
+	assert.ErrorIs(t, New().WaitReady(ctx), ErrNotStarted)
+}
+
+func Test_Edition(t *testing.T) {
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		_, _ = fmt.Fprint(w, `{"services": {}, "edition": "community"}`)
+	}))
+	defer server.Close()
+
+	stack := Attach(server.URL)
+	edition, err := stack.Edition(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "community", edition)
+
+	assert.ErrorIs(t, stack.RequirePro(context.Background()), ErrProRequired)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func Test_WaitReadyDoesNotBlockStack(t *testing.T) {
+
+	var ready int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := "initializing"
+		if atomic.LoadInt32(&ready) == 1 {
+			status = "running"
+		}
+		_, _ = fmt.Fprintf(w, `{"services": {"sqs": %q}}`, status)
+	}))
+	defer server.Close()
+
+	stack := Attach(server.URL)
+	stack.readyPollInterval = 10 * time.Millisecond
+
+	done := make(chan error, 1)
+	go func() { done <- stack.WaitReady(context.Background()) }()
+	time.Sleep(50 * time.Millisecond)
+
+	_, err := stack.EdgePort()
+	require.NoError(t, err)
+	atomic.StoreInt32(&ready, 1)
+	require.NoError(t, <-done)
+}
+
+func Test_WaitReadyTTYLogs(t *testing.T) {
+
+	var logReads int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/containers/ls/logs"):
+			// a TTY container log is the raw terminal output, without multiplexing headers
+			atomic.AddInt32(&logReads, 1)
+			_, _ = w.Write([]byte("init script done\r\nReady.\r\n"))
+		case strings.HasSuffix(r.URL.Path, "/containers/ls/json"):
+			_, _ = w.Write([]byte(`{"Id": "ls", "State": {"Status": "running", "Running": true}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cli, err := client.NewClientWithOpts(client.WithHost("tcp://"+server.Listener.Addr().String()), client.WithVersion("1.41"))
+	require.NoError(t, err)
+	stack := New()
+	stack.cli = cli
+	stack.containerID = "ls"
+	stack.tty = true
+	stack.started = true
+	stack.readyPollInterval = 10 * time.Millisecond
+	WithReadyLogLines([]string{"init script done"})(stack)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, stack.WaitReady(ctx))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&logReads))
+}
+
+func Test_WatchHealthDuringStartup(t *testing.T) {
+
+	var calls int32
+	edge := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := "starting"
+		if atomic.AddInt32(&calls, 1) > 2 {
+			status = "running"
+		}
+		_, _ = fmt.Fprintf(w, `{"services": {"sqs": %q}}`, status)
+	}))
+	defer edge.Close()
+
+	fake := newFakeDocker(t, "Ready.\r\n")
+	fake.edgePort = strconv.Itoa(edge.Listener.Addr().(*net.TCPAddr).Port)
+	stack := fake.stack(t)
+	require.NoError(t, stack.Start(false, WithNotInitWait()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := stack.WatchHealth(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "starting", (<-ch).Services["sqs"])
+	assert.Equal(t, "running", (<-ch).Services["sqs"])
+	require.NoError(t, stack.WaitReady(ctx))
+
+	cancel()
+	for range ch {
+	}
+}
+
+func Test_WaitReadyIgnoresServicesOfManagedStack(t *testing.T) {
+
+	var calls int32
+	edge := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := "initializing"
+		if atomic.AddInt32(&calls, 1) > 2 {
+			status = "running"
+		}
+		_, _ = fmt.Fprintf(w, `{"services": {"sqs": %q, "cloudwatch": "error"}}`, status)
+	}))
+	defer edge.Close()
+
+	fake := newFakeDocker(t, "Ready.\r\n")
+	fake.edgePort = strconv.Itoa(edge.Listener.Addr().(*net.TCPAddr).Port)
+	stack := fake.stack(t)
+	require.NoError(t, stack.Start(false, WithIgnoreServicesInReadiness("cloudwatch")))
+	assert.Greater(t, atomic.LoadInt32(&calls), int32(2))
+}
@@ -0,0 +1,23 @@
+package localstack
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// CallerIdentity returns the account and the ARN LocalStack attributes the requests of the stack
+// to, see WithAccountID
+func (s *Stack) CallerIdentity(ctx context.Context) (account string, arn string, err error) {
+	cfg, err := s.createTestConfig()
+	if err != nil {
+		return "", "", err
+	}
+	out, err := sts.NewFromConfig(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", "", fmt.Errorf("localstack: getting caller identity: %w", err)
+	}
+	return aws.ToString(out.Account), aws.ToString(out.Arn), nil
+}
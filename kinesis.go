@@ -0,0 +1,50 @@
+package localstack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	kinesistypes "github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+)
+
+// streamActiveTimeout bounds how long CreateKinesisStream waits for the stream to become active
+// when ctx has no earlier deadline
+const streamActiveTimeout = 30 * time.Second
+
+// CreateKinesisStream creates a stream with the given number of shards and waits until it is
+// ACTIVE, so records can be put right away. An existing stream is waited for as well
+func (s *Stack) CreateKinesisStream(ctx context.Context, name string, shards int32) error {
+	cfg, err := s.createTestConfig()
+	if err != nil {
+		return err
+	}
+	api := kinesis.NewFromConfig(cfg)
+
+	if _, err := api.CreateStream(ctx, &kinesis.CreateStreamInput{
+		StreamName: aws.String(name),
+		ShardCount: aws.Int32(shards),
+	}); err != nil {
+		var inUse *kinesistypes.ResourceInUseException
+		if !errors.As(err, &inUse) {
+			return fmt.Errorf("localstack: creating stream %s: %w", name, err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, streamActiveTimeout)
+	defer cancel()
+	status := kinesistypes.StreamStatusCreating
+	return poll(ctx, func() (bool, error) {
+		out, err := api.DescribeStream(ctx, &kinesis.DescribeStreamInput{StreamName: aws.String(name)})
+		if err != nil {
+			return false, fmt.Errorf("localstack: describing stream %s: %w", name, err)
+		}
+		status = out.StreamDescription.StreamStatus
+		return status == kinesistypes.StreamStatusActive, nil
+	}, func(err error) error {
+		return fmt.Errorf("localstack: stream %s is still %s: %w", name, status, err)
+	})
+}
@@ -1,23 +1,44 @@
 package localstack
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/docker/docker/api/types"
 	containertypes "github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
 	imagetypes "github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/docker/go-connections/nat"
+	"github.com/docker/go-units"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
 )
 
@@ -25,205 +46,1377 @@ const Port = "4566"
 const FixedPort = Port + "/tcp"
 const LocalStackImage = "localstack/localstack:3.0"
 
+// dockerSocketPath is mounted into the container so LocalStack can run Lambda functions in Docker
+const dockerSocketPath = "/var/run/docker.sock"
+
+// ErrDockerSocketMissing is returned by Start when the Docker socket should be mounted into the
+// container but does not exist on the host of a local daemon
+var ErrDockerSocketMissing = errors.New("localstack: docker socket not found on host")
+
+// stopTimeout is how long Docker waits for the container to exit before killing it
+const stopTimeout = 10 * time.Second
+
+// ErrNotStarted is returned by every method that needs a running stack when called before Start
+var ErrNotStarted = errors.New("localstack: stack not started")
+
+// ErrStopping is returned by Config while the stack is being drained
+var ErrStopping = errors.New("localstack: stack is stopping")
+
+// ErrInitTimeout is returned by Start when LocalStack did not become ready within WithInitTimeout
+var ErrInitTimeout = errors.New("localstack: init timeout exceeded")
+
+// autoPortAttempts bounds how often a port selected by WithAutoPort is retried when it was taken
+const autoPortAttempts = 5
+
+// logFailureLimit is how many consecutive failures to read the container logs make the init wait
+// fall back to the health endpoint
+const logFailureLimit = 5
+
+// localstackHostDomain resolves to 127.0.0.1 including all subdomains, see WithLocalstackHostDomain
+const localstackHostDomain = "localhost.localstack.cloud"
+
+// writablePaths are the directories LocalStack writes to, backed by tmpfs with WithReadOnlyRootFS
+var writablePaths = []string{"/tmp/localstack", "/var/lib/localstack"}
+
+// exitLogLines is how many log lines the error of a container that exited during init includes
+const exitLogLines = 20
+
 type Stack struct {
 	sync.RWMutex
-	started             bool
-	reuseExisting       bool
-	containerName       string
-	ctx                 context.Context
-	cli                 *client.Client
-	volumeMounts        map[string]string
-	initCompleteLogLine string
-	containerID         string
-	initTimeout         int
-	pm                  nat.PortMap
-	waitForInit         bool
+	started                 bool
+	reuseExisting           bool
+	containerName           string
+	ctx                     context.Context
+	cli                     *client.Client
+	volumeMounts            map[string]string
+	env                     map[string]string
+	initCompleteLogLine     string
+	containerID             string
+	initTimeout             time.Duration
+	pm                      nat.PortMap
+	waitForInit             bool
+	useTLS                  bool
+	ulimits                 []*units.Ulimit
+	autoPort                bool
+	readyPollInterval       time.Duration
+	attachedEndpoint        string
+	smokeTestService        string
+	containerUser           string
+	stopped                 chan struct{}
+	cfgMu                   sync.Mutex
+	cfg                     *aws.Config
+	edition                 string
+	rawClient               *http.Client
+	endpoint                atomic.Pointer[string]
+	autoStopCancel          context.CancelFunc
+	autoStopRun             uint64
+	capAdd                  []string
+	tty                     bool
+	noDockerSocket          bool
+	platform                string
+	timingCallback          func(phase string, d time.Duration)
+	defaultLogger           *slog.Logger
+	preCreateHook           func() error
+	postStopHook            func()
+	drainTimeout            time.Duration
+	draining                bool
+	inFlight                int64
+	readyLogLines           []string
+	seenLogLines            map[string]bool
+	retryMode               aws.RetryMode
+	retryMaxAttempts        int
+	logsUnavailable         bool
+	logFailures             int
+	logsSince               string
+	lastLogs                string
+	endpointHost            string
+	services                []string
+	startupOutput           io.Writer
+	logWriter               io.Writer
+	readyFile               string
+	lambdaCodeDir           string
+	region                  string
+	partition               string
+	hostConfigMutators      []func(*containertypes.HostConfig)
+	containerConfigMutators []func(*containertypes.Config)
+	dockerHealthcheck       bool
+	logger                  *slog.Logger
+	noAutoStop              bool
+	pullTimeout             time.Duration
+	accountID               string
+	sdkHTTPClient           *http.Client
+	stopSignal              string
+	readinessIgnored        map[string]bool
+	image                   string
+	allowLatest             bool
+	replaceExisting         bool
+	localstackHostDomain    bool
+	hookTimeout             time.Duration
+	bindIP                  string
+	captureLogs             io.Writer
+	dns                     []string
+	functionalProbe         bool
+	cmd                     []string
+	entrypoint              []string
+	useBaseEndpoint         bool
+	onReadyTimeout          func(s *Stack) error
+	readyScript             string
+	readOnlyRootFS          bool
+	maxRestarts             int
+	defaultCredentialChain  bool
+	extraPorts              map[nat.Port]nat.PortBinding
+	restarts                int
+	restarting              bool
 }
 
 // New returns the current stack instance
 func New() *Stack {
 	return &Stack{
-		ctx:         context.TODO(),
-		pm:          nat.PortMap{},
-		waitForInit: true,
+		ctx:               context.TODO(),
+		pm:                nat.PortMap{},
+		waitForInit:       true,
+		readyPollInterval: 500 * time.Millisecond,
+		defaultLogger:     newDefaultLogger(os.Stdout),
+		drainTimeout:      5 * time.Second,
+		region:            "us-east-1",
+		partition:         "aws",
+		image:             LocalStackImage,
+		bindIP:            "0.0.0.0",
+	}
+}
+
+// Attach returns a stack pointing at an already running LocalStack, e.g. a CI sidecar, whose
+// Start and Stop never touch Docker
+func Attach(endpointURL string) *Stack {
+	s := New()
+	s.attachedEndpoint = strings.TrimSuffix(endpointURL, "/")
+	s.started = true
+	s.publishEndpoint()
+	return s
+}
+
+// Clone returns a new, unstarted stack with the same configuration, e.g. to start a variant of a
+// configured stack with one additional option. Runtime state such as the container is not copied
+func (s *Stack) Clone() *Stack {
+	s.RLock()
+	defer s.RUnlock()
+
+	c := New()
+	copyOptions(c, s)
+	c.started = s.attachedEndpoint != ""
+	c.publishEndpoint()
+	return c
+}
+
+// snapshot returns a detached copy of the stack with its configuration and the runtime state of
+// the current run, which can be polled without holding the lock. The caller holds at least RLock
+func (s *Stack) snapshot() *Stack {
+	c := New()
+	copyOptions(c, s)
+	c.started = s.started
+	c.cli = s.cli
+	c.containerID = s.containerID
+	c.pm = copyMap(s.pm)
+	c.tty = s.tty
+	c.logsSince = s.logsSince
+	c.publishEndpoint()
+	return c
+}
+
+// copyOptions copies the configuration set by options from src to dst, leaving runtime state alone
+func copyOptions(dst, src *Stack) {
+	dst.reuseExisting = src.reuseExisting
+	dst.containerName = src.containerName
+	dst.ctx = src.ctx
+	dst.volumeMounts = copyMap(src.volumeMounts)
+	dst.env = copyMap(src.env)
+	dst.initCompleteLogLine = src.initCompleteLogLine
+	dst.initTimeout = src.initTimeout
+	dst.waitForInit = src.waitForInit
+	dst.useTLS = src.useTLS
+	dst.ulimits = nil
+	for _, ulimit := range src.ulimits {
+		u := *ulimit
+		dst.ulimits = append(dst.ulimits, &u)
+	}
+	dst.autoPort = src.autoPort
+	dst.readyPollInterval = src.readyPollInterval
+	dst.attachedEndpoint = src.attachedEndpoint
+	dst.smokeTestService = src.smokeTestService
+	dst.containerUser = src.containerUser
+	dst.capAdd = append([]string(nil), src.capAdd...)
+	dst.noDockerSocket = src.noDockerSocket
+	dst.platform = src.platform
+	dst.timingCallback = src.timingCallback
+	dst.defaultLogger = src.defaultLogger
+	dst.preCreateHook = src.preCreateHook
+	dst.postStopHook = src.postStopHook
+	dst.drainTimeout = src.drainTimeout
+	dst.readyLogLines = append([]string(nil), src.readyLogLines...)
+	dst.retryMode = src.retryMode
+	dst.retryMaxAttempts = src.retryMaxAttempts
+	dst.endpointHost = src.endpointHost
+	dst.services = append([]string(nil), src.services...)
+	dst.startupOutput = src.startupOutput
+	dst.logWriter = src.logWriter
+	dst.readyFile = src.readyFile
+	dst.lambdaCodeDir = src.lambdaCodeDir
+	dst.region = src.region
+	dst.partition = src.partition
+	dst.hostConfigMutators = append(([]func(*containertypes.HostConfig))(nil), src.hostConfigMutators...)
+	dst.containerConfigMutators = append(([]func(*containertypes.Config))(nil), src.containerConfigMutators...)
+	dst.dockerHealthcheck = src.dockerHealthcheck
+	dst.logger = src.logger
+	dst.noAutoStop = src.noAutoStop
+	dst.pullTimeout = src.pullTimeout
+	dst.accountID = src.accountID
+	dst.sdkHTTPClient = src.sdkHTTPClient
+	dst.stopSignal = src.stopSignal
+	dst.readinessIgnored = copyMap(src.readinessIgnored)
+	dst.image = src.image
+	dst.allowLatest = src.allowLatest
+	dst.replaceExisting = src.replaceExisting
+	dst.localstackHostDomain = src.localstackHostDomain
+	dst.hookTimeout = src.hookTimeout
+	dst.bindIP = src.bindIP
+	dst.captureLogs = src.captureLogs
+	dst.dns = append([]string(nil), src.dns...)
+	dst.functionalProbe = src.functionalProbe
+	dst.cmd = append([]string(nil), src.cmd...)
+	dst.entrypoint = append([]string(nil), src.entrypoint...)
+	dst.useBaseEndpoint = src.useBaseEndpoint
+	dst.onReadyTimeout = src.onReadyTimeout
+	dst.readyScript = src.readyScript
+	dst.readOnlyRootFS = src.readOnlyRootFS
+	dst.maxRestarts = src.maxRestarts
+	dst.defaultCredentialChain = src.defaultCredentialChain
+	dst.extraPorts = copyMap(src.extraPorts)
+}
+
+func copyMap[K comparable, V any](m map[K]V) map[K]V {
+	if m == nil {
+		return nil
+	}
+	c := make(map[K]V, len(m))
+	for k, v := range m {
+		c[k] = v
 	}
+	return c
 }
 
-// Start starts the stack instance with options, forces a restart if required
+// Start starts the stack instance with options, forces a restart if required. A forced restart
+// resets the configuration to the defaults of New and applies only the options passed to this call,
+// so options given to an earlier Start have to be passed again to be kept
 func (s *Stack) Start(forceRestart bool, opts ...StackOption) error {
 	s.Lock()
 	defer s.Unlock()
+	if s.attachedEndpoint != "" {
+		return nil
+	}
 	if s.started {
 		if !forceRestart {
 			return nil
 		}
-		if err := s.Stop(); err != nil {
+		if err := s.stop(context.Background()); err != nil {
 			return err
 		}
+		copyOptions(s, New())
 	}
 	for _, opt := range opts {
 		opt(s)
 	}
-	cli, err := client.NewClientWithOpts(client.FromEnv)
-	if err != nil {
-		return err
+	if s.cli == nil {
+		cli, err := client.NewClientWithOpts(client.FromEnv)
+		if err != nil {
+			return err
+		}
+		s.cli = cli
 	}
-
-	s.cli = cli
 	return s.start()
 }
 
 // Stop stops the stack instance
 func (s *Stack) Stop() error {
+	return s.StopContext(context.Background())
+}
+
+// StopContext is Stop bounded by ctx. When ctx is done before the container stopped, the error
+// is returned and the stack stays started, so the stop can be retried
+func (s *Stack) StopContext(ctx context.Context) error {
 	s.Lock()
 	defer s.Unlock()
-	return s.stop()
+	return s.stop(ctx)
+}
+
+// SetEnv sets an environment variable like WithEnv. The environment of a running container cannot
+// change, so if the stack is running it is restarted with all its options to apply the variable,
+// which discards the state of all services
+func (s *Stack) SetEnv(key, value string) error {
+	s.Lock()
+	defer s.Unlock()
+	WithEnv(key, value)(s)
+	if s.attachedEndpoint != "" || !s.started || s.containerID == "" {
+		return nil
+	}
+	if err := s.stop(context.Background()); err != nil {
+		return err
+	}
+	return s.start()
+}
+
+var _ io.Closer = (*Stack)(nil)
+
+// Close stops the stack and closes the Docker client it created, it is safe to call repeatedly. A
+// later Start creates a new client
+func (s *Stack) Close() error {
+	s.Lock()
+	defer s.Unlock()
+	if err := s.stop(context.Background()); err != nil {
+		return err
+	}
+	if s.cli == nil {
+		return nil
+	}
+	err := s.cli.Close()
+	s.cli = nil
+	return err
 }
 
-func (s *Stack) stop() error {
+func (s *Stack) stop(ctx context.Context) error {
 	if !s.started || s.containerID == "" {
 		return nil
 	}
-	timeoutSeconds := 10
-	if err := s.cli.ContainerStop(context.Background(), s.containerID, containertypes.StopOptions{
+	s.log().Info("stopping container", "container", s.containerID)
+	// the container is auto-removed on exit, so its log has to be attached to before stopping
+	var captured chan error
+	if s.captureLogs != nil {
+		reader, err := s.cli.ContainerLogs(ctx, s.containerID, containertypes.LogsOptions{
+			ShowStdout: true,
+			ShowStderr: true,
+			Follow:     true,
+		})
+		if err != nil {
+			s.log().Warn("could not capture container logs", "container", s.containerID, "error", err)
+		} else {
+			captured = make(chan error, 1)
+			go func(tty bool) {
+				defer func() { _ = reader.Close() }()
+				captured <- demuxLogs(s.captureLogs, reader, tty)
+			}(s.tty)
+		}
+	}
+	// a named container has to be fully auto-removed before a restart can reuse the name
+	var removed <-chan containertypes.WaitResponse
+	var waitErr <-chan error
+	if s.containerName != "" {
+		removed, waitErr = s.cli.ContainerWait(ctx, s.containerID, containertypes.WaitConditionRemoved)
+	}
+	timeoutSeconds := int(stopTimeout / time.Second)
+	if err := s.cli.ContainerStop(ctx, s.containerID, containertypes.StopOptions{
 		Timeout: &timeoutSeconds,
 	}); err != nil {
 		return err
 	}
+	if removed != nil {
+		select {
+		case <-removed:
+		case <-waitErr:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if captured != nil {
+		timer := time.NewTimer(stopTimeout)
+		select {
+		case <-captured:
+		case <-timer.C:
+			s.log().Warn("container log capture did not finish", "container", s.containerID)
+		case <-ctx.Done():
+			s.log().Warn("container log capture did not finish", "container", s.containerID, "error", ctx.Err())
+		}
+		timer.Stop()
+	}
 	s.containerID = ""
 	s.started = false
+	s.cancelAutoStop()
+	s.publishEndpoint()
+	s.resetConfig()
+	if s.stopped != nil {
+		close(s.stopped)
+		s.stopped = nil
+	}
 
+	if s.postStopHook != nil {
+		return s.runHook("post-stop", func() error {
+			s.postStopHook()
+			return nil
+		})
+	}
 	return nil
 }
 
+// runHook runs a lifecycle hook, bounded by WithHookTimeout. A hook that times out keeps running
+// in the background, hooks take no context that could cancel them
+func (s *Stack) runHook(name string, hook func() error) error {
+	if s.hookTimeout <= 0 {
+		if err := hook(); err != nil {
+			return fmt.Errorf("localstack: %s hook: %w", name, err)
+		}
+		return nil
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- hook() }()
+	timer := time.NewTimer(s.hookTimeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("localstack: %s hook: %w", name, err)
+		}
+		return nil
+	case <-timer.C:
+		return fmt.Errorf("localstack: %s hook timed out after %s", name, s.hookTimeout)
+	}
+}
+
+// EndpointURL returns the URL of the edge, empty while the stack is not running. It takes no lock,
+// the SDK clients resolve it while the WithAutoRestart supervisor may be recreating the container
 func (s *Stack) EndpointURL() string {
+	if endpoint := s.endpoint.Load(); endpoint != nil {
+		return *endpoint
+	}
+	return ""
+}
+
+// publishEndpoint makes the endpoint of the current state visible to EndpointURL, whenever the
+// container or its port bindings change. The caller holds the lock
+func (s *Stack) publishEndpoint() {
+	endpoint := s.endpointURL()
+	s.endpoint.Store(&endpoint)
+}
+
+func (s *Stack) endpointURL() string {
+	if s.attachedEndpoint != "" {
+		return s.attachedEndpoint
+	}
 
 	if s.containerID != "" {
-		if port, ok := s.pm[nat.Port(FixedPort)]; ok {
-			return "http://" + port[0].HostIP + ":" + port[0].HostPort
+		if port, ok := s.pm[nat.Port(FixedPort)]; ok && len(port) > 0 && port[0].HostPort != "" {
+			scheme := "http://"
+			if s.useTLS {
+				scheme = "https://"
+			}
+			host := port[0].HostIP
+			if s.endpointHost != "" {
+				host = s.endpointHost
+			}
+			return scheme + net.JoinHostPort(host, port[0].HostPort)
 		}
 
 	}
 	return ""
 }
 
-func (s *Stack) start() error {
+// EdgePort returns the host port the edge service is reachable on, e.g. to build addresses that
+// are not URLs
+func (s *Stack) EdgePort() (int, error) {
+	s.RLock()
+	defer s.RUnlock()
+	if s.attachedEndpoint != "" {
+		endpoint, err := url.Parse(s.attachedEndpoint)
+		if err != nil {
+			return 0, fmt.Errorf("localstack: parsing attached endpoint: %w", err)
+		}
+		port := endpoint.Port()
+		if port == "" {
+			port = "80"
+			if endpoint.Scheme == "https" {
+				port = "443"
+			}
+		}
+		return strconv.Atoi(port)
+	}
+	port, err := s.mappedPort(FixedPort)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(port)
+}
+
+// MappedPort returns the host port a container port, e.g. the edge port 4566 or one added with
+// WithPort, is published on. A port without protocol is taken as TCP
+func (s *Stack) MappedPort(containerPort string) (string, error) {
+	s.RLock()
+	defer s.RUnlock()
+	return s.mappedPort(containerPort)
+}
 
-	go func() {
-		<-s.ctx.Done()
-		fmt.Printf("Stopping Container: %s\n", s.containerID)
-		if err := s.stop(); err != nil {
-			panic(err)
+func (s *Stack) mappedPort(containerPort string) (string, error) {
+	if !s.started {
+		return "", ErrNotStarted
+	}
+	port, err := parseContainerPort(containerPort)
+	if err != nil {
+		return "", err
+	}
+	bindings := s.pm[port]
+	if len(bindings) == 0 || bindings[0].HostPort == "" {
+		return "", fmt.Errorf("localstack: container port %s is not published", port)
+	}
+	return bindings[0].HostPort, nil
+}
+
+// parseContainerPort parses a port like "4571" or "4571/udp"
+func parseContainerPort(containerPort string) (nat.Port, error) {
+	proto, port := nat.SplitProtoPort(containerPort)
+	if _, err := nat.ParsePort(port); err != nil || port == "" || (proto != "tcp" && proto != "udp" && proto != "sctp") {
+		return "", fmt.Errorf("localstack: invalid container port %q", containerPort)
+	}
+	return nat.NewPort(proto, port)
+}
+
+// bindPorts sets the port bindings the container is created with, the edge on hostPort (empty for
+// one chosen by Docker) and the ports added with WithPort. Bindings of a previous run are dropped,
+// its options may have been reset since
+func (s *Stack) bindPorts(hostPort string) {
+	s.pm = nat.PortMap{}
+	s.pm[nat.Port(FixedPort)] = []nat.PortBinding{{HostIP: s.bindIP, HostPort: hostPort}}
+	for port, binding := range s.extraPorts {
+		s.pm[port] = []nat.PortBinding{{HostIP: s.bindIP, HostPort: binding.HostPort}}
+	}
+}
+
+// DockerClient returns the Docker client the stack was started with, or nil before the first Start,
+// e.g. to run related containers on the same daemon. It belongs to the stack and is reused across
+// restarts, so callers must not close it
+func (s *Stack) DockerClient() *client.Client {
+	s.RLock()
+	defer s.RUnlock()
+	return s.cli
+}
+
+// Inspect returns the raw Docker inspect result of the running container
+func (s *Stack) Inspect(ctx context.Context) (types.ContainerJSON, error) {
+	s.RLock()
+	defer s.RUnlock()
+	if !s.started || s.containerID == "" {
+		return types.ContainerJSON{}, ErrNotStarted
+	}
+	return s.cli.ContainerInspect(ctx, s.containerID)
+}
+
+func (s *Stack) start() (err error) {
+	// a container that was created but did not become a started stack would be unreachable for
+	// Stop, and AutoRemove only fires on an exit
+	defer func() {
+		if err != nil && !s.started && s.containerID != "" {
+			s.discardContainer()
+		}
+		if err != nil && !s.started && !s.restarting {
+			s.cancelAutoStop()
 		}
 	}()
 
-	s.pm[nat.Port(FixedPort)] = []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: ""}}
+	// a restart by the WithAutoRestart supervisor keeps the watcher of the first start. Every other
+	// start gets its own watcher, which stop cancels, so the context of an earlier Start cannot
+	// stop a later run
+	if !s.noAutoStop && !s.restarting {
+		s.cancelAutoStop()
+		watchCtx, cancel := context.WithCancel(context.Background())
+		s.autoStopCancel = cancel
+		s.autoStopRun++
+		go func(ctx context.Context, run uint64) {
+			select {
+			case <-ctx.Done():
+			case <-watchCtx.Done():
+				return
+			}
+			s.Lock()
+			defer s.Unlock()
+			if s.autoStopRun != run {
+				return
+			}
+			s.log().Info("context done, stopping container", "container", s.containerID)
+			if err := s.stop(context.Background()); err != nil {
+				s.log().Error("could not stop container", "container", s.containerID, "error", err)
+			}
+		}(s.ctx, s.autoStopRun)
+	}
 
-	if err := s.ensureImage(LocalStackImage); err != nil {
+	s.log().Debug("starting localstack", "image", s.image)
+	if !s.allowLatest && floatingTag(s.image) {
+		if _, warned := floatingTagWarned.LoadOrStore(s.image, true); !warned {
+			s.log().Warn("image uses a floating tag, tests may break when it moves; pin a version or pass WithAllowLatest", "image", s.image)
+		}
+	}
+	phaseStart := time.Now()
+	if err := s.pullImage(); err != nil {
 		return err
 	}
-
-	resp, err := s.cli.ContainerCreate(s.ctx,
-		&containertypes.Config{
-			Image:        LocalStackImage,
-			Tty:          true,
-			AttachStdout: true,
-			AttachStderr: true,
-		}, &containertypes.HostConfig{
-			PortBindings: s.pm,
-			Mounts:       s.getVolumeMounts(),
-			AutoRemove:   true,
-		}, nil, nil, s.containerName)
-	if err != nil {
-		if s.reuseExisting && strings.Contains(err.Error(), fmt.Sprintf("The container name \"%s\" is already in use by container", s.containerName)) {
-			return nil
+	s.reportPhase("pull", phaseStart)
+	// checked before the container exists, so a mismatch leaves nothing running
+	if s.platform == "" {
+		if err := s.checkArchitecture(s.image); err != nil {
+			return err
 		}
-		return fmt.Errorf("localstack: could not create container: %w", err)
 	}
 
-	s.containerID = resp.ID
+	if s.lambdaCodeDir != "" && s.noDockerSocket {
+		return fmt.Errorf("localstack: WithLambdaCodeMount requires the docker socket mount, remove WithoutDockerSocket")
+	}
+	if !s.noDockerSocket {
+		if _, err := os.Stat(dockerSocketPath); err != nil {
+			// the daemon resolves the bind source, only a local one sees the same filesystem
+			if daemonIsLocal(s.cli.DaemonHost()) {
+				return fmt.Errorf("%w: %s is required for Lambda, use WithoutDockerSocket if it is not needed", ErrDockerSocketMissing, dockerSocketPath)
+			}
+			s.log().Warn("docker socket not found on this host, assuming the remote daemon provides it", "socket", dockerSocketPath, "daemon", s.cli.DaemonHost())
+		}
+	}
 
-	if err := s.cli.ContainerStart(s.ctx, s.containerID, containertypes.StartOptions{}); err != nil {
+	if s.preCreateHook != nil {
+		if err := s.runHook("pre-create", s.preCreateHook); err != nil {
+			return err
+		}
+	}
+	if err := s.checkMountSources(); err != nil {
 		return err
 	}
+	if s.readOnlyRootFS {
+		if persistence := strings.ToLower(s.env["PERSISTENCE"]); persistence == "1" || persistence == "true" {
+			return fmt.Errorf("localstack: WithReadOnlyRootFS cannot be combined with PERSISTENCE, which needs a writable data directory")
+		}
+	}
 
-	start := time.Now()
-	if s.waitForInit {
-		for {
-			if s.initTimeout > 0 && time.Since(start) > time.Duration(s.initTimeout)*time.Second {
-				return fmt.Errorf("localstack: init timeout exceeded (%d seconds)", s.initTimeout)
+	// a restarted container keeps the port of the crashed one if it is still free
+	previousPort := ""
+	if s.restarting {
+		if bindings := s.pm[nat.Port(FixedPort)]; len(bindings) > 0 {
+			previousPort = bindings[0].HostPort
+		}
+	}
+
+	replaced := false
+	for attempt := 1; ; attempt++ {
+		hostPort := previousPort
+		if hostPort == "" && s.autoPort {
+			port, err := freePort()
+			if err != nil {
+				return fmt.Errorf("localstack: could not select a free port: %w", err)
+			}
+			hostPort = port
+		}
+		s.bindPorts(hostPort)
+
+		containerConfig := s.containerConfig()
+		s.tty = containerConfig.Tty
+		phaseStart = time.Now()
+		resp, err := s.cli.ContainerCreate(s.ctx, containerConfig, s.hostConfig(), nil, s.ociPlatform(), s.containerName)
+		if err != nil {
+			if s.reuseExisting && s.isNameConflict(err) {
+				return nil
 			}
-			if s.initComplete() {
-				break
+			if s.replaceExisting && !replaced && s.isNameConflict(err) {
+				// a leftover of a crashed run, remove it and create the container once more
+				replaced = true
+				s.log().Info("removing existing container", "name", s.containerName)
+				if err := s.cli.ContainerRemove(s.ctx, s.containerName, containertypes.RemoveOptions{Force: true, RemoveVolumes: true}); err != nil && !client.IsErrNotFound(err) {
+					return fmt.Errorf("localstack: could not remove existing container %s: %w", s.containerName, err)
+				}
+				continue
 			}
-			time.Sleep(500 * time.Millisecond)
+			return fmt.Errorf("localstack: could not create container: %w", err)
+		}
+		s.reportPhase("create", phaseStart)
+
+		s.containerID = resp.ID
+		s.log().Debug("container created", "container", s.containerID)
+
+		phaseStart = time.Now()
+		err = s.cli.ContainerStart(s.ctx, s.containerID, containertypes.StartOptions{})
+		if err == nil {
+			s.reportPhase("start", phaseStart)
+			break
+		}
+		if previousPort != "" && isPortConflict(err) {
+			s.log().Warn("previous port is taken, restarting on another port", "port", previousPort)
+			previousPort = ""
+			_ = s.cli.ContainerRemove(s.ctx, s.containerID, containertypes.RemoveOptions{Force: true})
+			s.containerID = ""
+			continue
+		}
+		// another process may have bound the selected port between freePort and ContainerStart
+		if !s.autoPort || attempt == autoPortAttempts || !isPortConflict(err) {
+			return err
 		}
+		_ = s.cli.ContainerRemove(s.ctx, s.containerID, containertypes.RemoveOptions{Force: true})
+		s.containerID = ""
 	}
 
+	if s.logWriter != nil {
+		go func(containerID string) { _ = s.followLogs(context.Background(), containerID, s.logWriter) }(s.containerID)
+	}
+	if s.startupOutput != nil {
+		followCtx, cancel := context.WithCancel(s.ctx)
+		followed := make(chan struct{})
+		go func(containerID string) {
+			defer close(followed)
+			_ = s.followLogs(followCtx, containerID, s.startupOutput)
+		}(s.containerID)
+		defer func() {
+			cancel()
+			<-followed
+		}()
+	}
+
+	// the port is resolved before the init wait so the edge can be polled while waiting
 	cont, err := s.cli.ContainerInspect(s.ctx, s.containerID)
 	if err != nil {
 		return err
 	}
 	ports := cont.NetworkSettings.Ports
 	bindings := ports[nat.Port(FixedPort)]
-	s.pm[nat.Port(FixedPort)] = []nat.PortBinding{{HostIP: "localhost", HostPort: bindings[0].HostPort}}
+	if len(bindings) == 0 {
+		return fmt.Errorf("localstack: container does not publish the edge port %s", FixedPort)
+	}
+	s.pm[nat.Port(FixedPort)] = []nat.PortBinding{{HostIP: s.endpointIP(), HostPort: bindings[0].HostPort}}
+	for port := range s.extraPorts {
+		if bindings := ports[port]; len(bindings) > 0 {
+			s.pm[port] = []nat.PortBinding{{HostIP: s.endpointIP(), HostPort: bindings[0].HostPort}}
+		}
+	}
+	s.publishEndpoint()
+
+	// readiness only considers the log of this run, stale lines of a previous run of the same
+	// container must not satisfy it. The daemon's own start time avoids host clock skew
+	s.logsSince = ""
+	if cont.State != nil {
+		s.logsSince = cont.State.StartedAt
+	}
+
+	start := time.Now()
+	var initErr error
+	if s.waitForInit {
+		initErr = s.waitReady(s.ctx, s.initTimeout)
+		if initErr != nil && (s.onReadyTimeout == nil || !errors.Is(initErr, ErrInitTimeout)) {
+			return initErr
+		}
+		s.reportPhase("init", start)
+	}
 
+	s.resetConfig()
 	s.started = true
+	s.stopped = make(chan struct{})
+	if initErr != nil {
+		// the stack counts as started while the policy runs, so it can inspect, read logs or stop
+		s.Unlock()
+		err := s.onReadyTimeout(s)
+		s.Lock()
+		if err != nil {
+			_ = s.stop(context.Background())
+			return err
+		}
+		s.log().Warn("proceeding although localstack is not ready", "container", s.containerID, "error", initErr)
+	}
+	if s.functionalProbe {
+		if err := s.probe(); err != nil {
+			_ = s.stop(context.Background())
+			return fmt.Errorf("localstack: functional probe against %s failed: %w", s.probeService(), err)
+		}
+	}
+	if s.maxRestarts > 0 {
+		go s.supervise(s.containerID)
+	}
+	s.log().Info("localstack ready", "container", s.containerID, "endpoint", s.EndpointURL())
 	return nil
 }
 
-func (s *Stack) ensureImage(imageName string) error {
+// cancelAutoStop ends the context watcher of the current run, if any
+func (s *Stack) cancelAutoStop() {
+	if s.autoStopCancel != nil {
+		s.autoStopCancel()
+		s.autoStopCancel = nil
+	}
+}
+
+// discardContainer force-removes the container of a failed start
+func (s *Stack) discardContainer() {
+	if err := s.cli.ContainerRemove(context.Background(), s.containerID, containertypes.RemoveOptions{Force: true}); err != nil && !client.IsErrNotFound(err) {
+		s.log().Warn("could not remove container of failed start", "container", s.containerID, "error", err)
+	}
+	s.containerID = ""
+	s.publishEndpoint()
+}
+
+// WaitReady blocks until the stack is ready according to the configured readiness strategy (ready
+// log lines, WithReadyFile, WithReadyScript, WithDockerHealthcheck), e.g. after starting with
+// WithNotInitWait. An attached stack is ready once its health endpoint reports every service usable
+func (s *Stack) WaitReady(ctx context.Context) error {
+	s.RLock()
+	if !s.started || (s.containerID == "" && s.attachedEndpoint == "") {
+		s.RUnlock()
+		return ErrNotStarted
+	}
+	// the wait may be unbounded, polling a snapshot keeps Stop, Inspect and the accessors usable
+	// meanwhile. A concurrent Stop ends the wait with the exited container error
+	snapshot := s.snapshot()
+	s.RUnlock()
+
+	defer snapshot.resetConfig()
+	return snapshot.waitReady(ctx, 0)
+}
+
+// waitReady polls the readiness of the stack until it is ready, the container exited, timeout (if
+// positive) passed or ctx is done
+func (s *Stack) waitReady(ctx context.Context, timeout time.Duration) error {
+	s.seenLogLines = make(map[string]bool)
+	s.logsUnavailable = false
+	s.logFailures = 0
+	s.lastLogs = ""
+
+	b := newBackoff(s.readyPollInterval)
+	start := time.Now()
+	for {
+		if timeout > 0 && time.Since(start) > timeout {
+			return fmt.Errorf("%w (%s)", ErrInitTimeout, timeout)
+		}
+		if s.attachedEndpoint != "" {
+			if s.healthReady(ctx) {
+				return nil
+			}
+		} else {
+			complete, err := s.initComplete(ctx)
+			if err != nil {
+				return err
+			}
+			if complete {
+				return nil
+			}
+			if err := s.exitedError(ctx); err != nil {
+				return err
+			}
+		}
+
+		if err := b.wait(ctx); err != nil {
+			return fmt.Errorf("localstack: waiting for readiness: %w", err)
+		}
+	}
+}
+
+// daemonIsLocal reports whether the daemon shares the filesystem of this host: a unix socket on
+// Linux. Remote daemons (tcp://, ssh://) and the VMs of Docker Desktop or colima resolve bind
+// sources in their own filesystem
+func daemonIsLocal(daemonHost string) bool {
+	return strings.HasPrefix(daemonHost, "unix://") && runtime.GOOS == "linux"
+}
+
+// checkArchitecture fails when the image runs under emulation because it was built for another
+// architecture than the Docker host, which otherwise surfaces as a crash or init timeout
+func (s *Stack) checkArchitecture(imageRef string) error {
+	image, _, err := s.cli.ImageInspectWithRaw(s.ctx, imageRef)
+	if err != nil {
+		return fmt.Errorf("localstack: inspecting image: %w", err)
+	}
+	version, err := s.cli.ServerVersion(s.ctx)
+	if err != nil {
+		return fmt.Errorf("localstack: querying docker version: %w", err)
+	}
+	if image.Architecture != "" && version.Arch != "" && image.Architecture != version.Arch {
+		return fmt.Errorf("localstack: image architecture %s does not match host %s; consider WithPlatform", image.Architecture, version.Arch)
+	}
+	return nil
+}
+
+// log returns the logger set by WithLogger, else the text logger writing to the WithLogOutput
+// writer
+func (s *Stack) log() *slog.Logger {
+	if s.logger != nil {
+		return s.logger
+	}
+	if s.defaultLogger == nil {
+		return slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	return s.defaultLogger
+}
+
+// newDefaultLogger only reports warnings and errors, lifecycle events are left to WithLogger so
+// that the stack keeps the output of test suites clean
+func newDefaultLogger(w io.Writer) *slog.Logger {
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: slog.LevelWarn}))
+}
+
+// reportPhase passes the time spent in a startup phase to the WithTimingCallback callback
+func (s *Stack) reportPhase(phase string, since time.Time) {
+	if s.timingCallback != nil {
+		s.timingCallback(phase, time.Since(since))
+	}
+}
+
+// endpointIP returns the host the edge is reached at, the bind IP unless it is a wildcard
+func (s *Stack) endpointIP() string {
+	if ip := net.ParseIP(s.bindIP); ip != nil && !ip.IsUnspecified() {
+		return s.bindIP
+	}
+	return "localhost"
+}
+
+// freePort asks the kernel for an ephemeral port that is free at the time of the call
+func freePort() (string, error) {
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = l.Close() }()
+	return strconv.Itoa(l.Addr().(*net.TCPAddr).Port), nil
+}
+
+// isNameConflict reports whether ContainerCreate failed because the container name is taken
+func (s *Stack) isNameConflict(err error) bool {
+	return strings.Contains(err.Error(), fmt.Sprintf("The container name \"/%s\" is already in use by container", s.containerName)) ||
+		strings.Contains(err.Error(), fmt.Sprintf("The container name \"%s\" is already in use by container", s.containerName))
+}
+
+func isPortConflict(err error) bool {
+	return strings.Contains(err.Error(), "port is already allocated") || strings.Contains(err.Error(), "address already in use")
+}
+
+// floatingTagWarned holds the images already warned about, the warning is logged once per process
+var floatingTagWarned sync.Map
+
+// floatingTag reports whether image has no tag or the "latest" tag and is not pinned by digest
+func floatingTag(image string) bool {
+	if strings.Contains(image, "@") {
+		return false
+	}
+	name := image[strings.LastIndex(image, "/")+1:]
+	i := strings.LastIndex(name, ":")
+	return i < 0 || name[i+1:] == "latest"
+}
+
+// pullImage ensures the image is present, bounded by the WithImagePullTimeout budget
+func (s *Stack) pullImage() error {
+	if s.pullTimeout <= 0 {
+		return s.ensureImage(s.ctx, s.image)
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, s.pullTimeout)
+	defer cancel()
+	err := s.ensureImage(ctx, s.image)
+	if err != nil && s.ctx.Err() == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("localstack: pulling image %s: timeout exceeded (%s): %w", s.image, s.pullTimeout, ctx.Err())
+	}
+	return err
+}
+
+func (s *Stack) ensureImage(ctx context.Context, imageName string) error {
 
 	f := filters.NewArgs()
 	f.Add("reference", imageName)
 
-	images, err := s.cli.ImageList(s.ctx, imagetypes.ListOptions{
+	images, err := s.cli.ImageList(ctx, imagetypes.ListOptions{
 		Filters: f,
 	})
 
 	if err != nil {
-		return err
+		return fmt.Errorf("localstack: listing images: %w", err)
+	}
+
+	if hasTag(images, imageName) {
+		matches, err := s.matchesPlatform(ctx, imageName)
+		if err != nil || matches {
+			return err
+		}
+		s.log().Info("local image is built for another platform", "image", imageName, "platform", s.platform)
+	}
+
+	s.log().Info("pulling image", "image", imageName, "platform", s.platform)
+	resp, err := s.cli.ImagePull(ctx, imageName, imagetypes.PullOptions{Platform: s.platform})
+	if err != nil {
+		return fmt.Errorf("localstack: pulling image %s: %w", imageName, err)
 	}
 
+	defer func() { _ = resp.Close() }()
+	if err := copyContext(ctx, io.Discard, resp); err != nil {
+		return fmt.Errorf("localstack: pulling image %s: %w", imageName, err)
+	}
+	return nil
+}
+
+func hasTag(images []imagetypes.Summary, imageName string) bool {
 	for _, image := range images {
 		for _, tag := range image.RepoTags {
 			if tag == imageName {
-				return nil
+				return true
 			}
 		}
 	}
+	return false
+}
 
-	resp, err := s.cli.ImagePull(s.ctx, imageName, imagetypes.PullOptions{})
+// matchesPlatform reports whether the local image was built for the WithPlatform platform, any
+// image matches without one
+func (s *Stack) matchesPlatform(ctx context.Context, imageName string) (bool, error) {
+	platform := s.ociPlatform()
+	if platform == nil {
+		return true, nil
+	}
+	image, _, err := s.cli.ImageInspectWithRaw(ctx, imageName)
 	if err != nil {
-		return err
+		return false, fmt.Errorf("localstack: inspecting image: %w", err)
 	}
+	return image.Os == platform.OS && image.Architecture == platform.Architecture &&
+		(platform.Variant == "" || image.Variant == platform.Variant), nil
+}
 
-	defer func() { _ = resp.Close() }()
-	_, err = io.Copy(io.Discard, resp)
+// copyContext copies r to w until EOF or until ctx is done, in which case r is closed to unblock
+// the pending read and ctx.Err() is returned
+func copyContext(ctx context.Context, w io.Writer, r io.ReadCloser) error {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = r.Close()
+		case <-done:
+		}
+	}()
+
+	_, err := io.Copy(w, r)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
 	return err
 }
 
-func (s *Stack) initComplete() bool {
-	reader, err := s.cli.ContainerLogs(s.ctx, s.containerID, containertypes.LogsOptions{
+func (s *Stack) initComplete(ctx context.Context) (bool, error) {
+	complete, err := s.containerReady(ctx)
+	if err != nil || !complete {
+		return false, err
+	}
+	// the services the tests rely on have to be usable too, see WithIgnoreServicesInReadiness. The
+	// fallback of logLinesComplete has checked the health endpoint already
+	if len(s.readinessIgnored) > 0 && !s.logsUnavailable {
+		return s.healthReady(ctx), nil
+	}
+	return true, nil
+}
+
+// containerReady applies the configured readiness strategy to the container
+func (s *Stack) containerReady(ctx context.Context) (bool, error) {
+	if s.dockerHealthcheck {
+		return s.containerHealthy(ctx)
+	}
+	complete, err := s.logLinesComplete(ctx)
+	if err != nil || !complete {
+		return false, err
+	}
+	if s.readyFile != "" {
+		if _, err := s.cli.ContainerStatPath(ctx, s.containerID, s.readyFile); err != nil {
+			return false, nil
+		}
+	}
+	if s.readyScript != "" {
+		return s.readyScriptSucceeds(ctx)
+	}
+	return true, nil
+}
+
+// readyScriptSucceeds runs the WithReadyScript snippet in the container and reports whether it
+// exited with 0
+func (s *Stack) readyScriptSucceeds(ctx context.Context) (bool, error) {
+	exec, err := s.cli.ContainerExecCreate(ctx, s.containerID, types.ExecConfig{
+		Cmd:          []string{"sh", "-c", s.readyScript},
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return false, nil
+	}
+	attach, err := s.cli.ContainerExecAttach(ctx, exec.ID, types.ExecStartCheck{})
+	if err != nil {
+		return false, nil
+	}
+	_, _ = io.Copy(io.Discard, attach.Reader)
+	attach.Close()
+
+	inspect, err := s.cli.ContainerExecInspect(ctx, exec.ID)
+	if err != nil || inspect.Running {
+		return false, nil
+	}
+	return inspect.ExitCode == 0, nil
+}
+
+// containerHealthy reports whether Docker considers the container healthy per its HEALTHCHECK
+func (s *Stack) containerHealthy(ctx context.Context) (bool, error) {
+	cont, err := s.cli.ContainerInspect(ctx, s.containerID)
+	if err != nil {
+		return false, nil
+	}
+	if cont.State == nil || cont.State.Health == nil {
+		return false, nil
+	}
+	switch cont.State.Health.Status {
+	case types.Healthy:
+		return true, nil
+	case types.Unhealthy:
+		// failed checks of a booting container are no verdict yet
+		if startedAt, err := time.Parse(time.RFC3339Nano, cont.State.StartedAt); err == nil && time.Since(startedAt) < s.healthcheckStartPeriod() {
+			return false, nil
+		}
+		return false, fmt.Errorf("localstack: container reported unhealthy")
+	}
+	return false, nil
+}
+
+// defaultHealthcheckStartPeriod is how long a booting container may fail its healthcheck when no
+// init timeout is set
+const defaultHealthcheckStartPeriod = 2 * time.Minute
+
+// healthcheckStartPeriod gives LocalStack its whole init budget to boot before failed healthchecks
+// count
+func (s *Stack) healthcheckStartPeriod() time.Duration {
+	if s.initTimeout > 0 {
+		return s.initTimeout
+	}
+	return defaultHealthcheckStartPeriod
+}
+
+// logLinesComplete reports whether all required log lines were seen, or when the logs cannot be
+// read whether the health endpoint reports the services as usable
+func (s *Stack) logLinesComplete(ctx context.Context) (bool, error) {
+	if s.logsUnavailable {
+		return s.healthReady(ctx), nil
+	}
+	logContent, err := s.containerLogs(ctx)
+	if err != nil {
+		// restricted setups may not expose the logs API, the health endpoint still tells readiness.
+		// Other errors are common while the container is young and are retried
+		if s.logFailures++; s.logFailures < logFailureLimit && !errdefs.IsForbidden(err) && !errdefs.IsUnauthorized(err) && !errdefs.IsNotImplemented(err) {
+			s.log().Debug("could not read container logs", "error", err)
+			return false, nil
+		}
+		s.logsUnavailable = true
+		s.log().Warn("container logs unavailable, waiting on the health endpoint instead", "error", err)
+		return s.healthReady(ctx), nil
+	}
+	s.logFailures = 0
+
+	s.lastLogs = logContent
+	complete := true
+	for _, line := range s.requiredLogLines() {
+		if !s.seenLogLines[line] && strings.Contains(logContent, line) {
+			s.seenLogLines[line] = true
+		}
+		complete = complete && s.seenLogLines[line]
+	}
+	if complete {
+		return true, nil
+	}
+	if line, ok := fatalLogLine(logContent); ok {
+		return false, fmt.Errorf("localstack: container failed during init: %s", line)
+	}
+	return false, nil
+}
+
+// exitedError fails the init wait fast when the container is no longer running, reporting the
+// exit code and the end of its log
+func (s *Stack) exitedError(ctx context.Context) error {
+	cont, err := s.cli.ContainerInspect(ctx, s.containerID)
+	if client.IsErrNotFound(err) {
+		// the container is auto-removed, the logs read during the wait are all that is left
+		return fmt.Errorf("localstack: container exited during init and was removed%s", logTail(s.lastLogs))
+	}
+	if err != nil || cont.State == nil {
+		return nil
+	}
+	if cont.State.Status != "exited" && cont.State.Status != "dead" {
+		return nil
+	}
+
+	logContent, err := s.containerLogs(ctx)
+	if err != nil {
+		logContent = s.lastLogs
+	}
+	return fmt.Errorf("localstack: container exited during init with code %d%s", cont.State.ExitCode, logTail(logContent))
+}
+
+// logTail formats the last exitLogLines lines of a log for appending to an error
+func logTail(logContent string) string {
+	lines := strings.Split(strings.TrimRight(logContent, "\n"), "\n")
+	if len(lines) > exitLogLines {
+		lines = lines[len(lines)-exitLogLines:]
+	}
+	tail := strings.Join(lines, "\n")
+	if strings.TrimSpace(tail) == "" {
+		return ""
+	}
+	return ", last log lines:\n" + tail
+}
+
+// requiredLogLines returns the lines that all have to be logged before init is complete
+func (s *Stack) requiredLogLines() []string {
+	lines := append([]string(nil), s.readyLogLines...)
+	if s.initCompleteLogLine != "" {
+		lines = append(lines, s.initCompleteLogLine)
+	}
+	if len(lines) == 0 && s.readyFile == "" && s.readyScript == "" {
+		lines = append(lines, "Ready.")
+	}
+	return lines
+}
+
+// containerLogs returns the combined stdout and stderr of the container since its current start
+func (s *Stack) containerLogs(ctx context.Context) (string, error) {
+	reader, err := s.cli.ContainerLogs(ctx, s.containerID, containertypes.LogsOptions{
 		ShowStdout: true,
+		ShowStderr: true,
+		Since:      s.logsSince,
 		Follow:     false,
 	})
 	if err != nil {
-		return false
+		return "", err
 	}
 	defer func() { _ = reader.Close() }()
 
-	logContent, err := io.ReadAll(reader)
+	var buf bytes.Buffer
+	if err := demuxLogs(&buf, reader, s.tty); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// followLogs streams the container log to w until ctx is done or the container exits
+func (s *Stack) followLogs(ctx context.Context, containerID string, w io.Writer) error {
+	reader, err := s.cli.ContainerLogs(ctx, containerID, containertypes.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     true,
+	})
 	if err != nil {
-		return false
+		return err
+	}
+	defer func() { _ = reader.Close() }()
+	return demuxLogs(w, reader, s.tty)
+}
+
+// demuxLogs copies a container log stream to w. Without a TTY Docker multiplexes stdout and
+// stderr into frames, with a TTY the stream is already raw
+func demuxLogs(w io.Writer, r io.Reader, tty bool) error {
+	if tty {
+		_, err := io.Copy(w, r)
+		return err
+	}
+	_, err := stdcopy.StdCopy(w, w, r)
+	return err
+}
+
+// fatalLogPatterns mark log lines after which LocalStack will not become ready. Tracebacks are no
+// such mark, LocalStack also logs them for errors it recovers from, e.g. a failing init script
+var fatalLogPatterns = []string{"Error starting infrastructure", "LocalstackExit"}
+
+func fatalLogLine(logContent string) (string, bool) {
+	for _, line := range strings.Split(logContent, "\n") {
+		line = strings.TrimSpace(line)
+		for _, pattern := range fatalLogPatterns {
+			if strings.Contains(line, pattern) {
+				return line, true
+			}
+		}
+	}
+	return "", false
+}
+
+// ociPlatform converts the configured "os/arch[/variant]" platform for ContainerCreate
+func (s *Stack) ociPlatform() *ocispec.Platform {
+	if s.platform == "" {
+		return nil
+	}
+	parts := strings.Split(s.platform, "/")
+	platform := &ocispec.Platform{OS: parts[0], Architecture: parts[1]}
+	if len(parts) > 2 {
+		platform.Variant = parts[2]
+	}
+	return platform
+}
+
+func (s *Stack) containerConfig() *containertypes.Config {
+	containerConfig := &containertypes.Config{
+		Image:        s.image,
+		Env:          s.getEnv(),
+		User:         s.containerUser,
+		StopSignal:   s.stopSignal,
+		Cmd:          s.cmd,
+		Entrypoint:   s.entrypoint,
+		Tty:          true,
+		AttachStdout: true,
+		AttachStderr: true,
+	}
+	// the image only exposes the edge and the external service port range
+	if len(s.extraPorts) > 0 {
+		containerConfig.ExposedPorts = nat.PortSet{}
+		for port := range s.extraPorts {
+			containerConfig.ExposedPorts[port] = struct{}{}
+		}
 	}
+	if s.dockerHealthcheck {
+		containerConfig.Healthcheck = &containertypes.HealthConfig{
+			Test:        []string{"CMD-SHELL", "curl -sf http://localhost:" + Port + "/_localstack/health || exit 1"},
+			Interval:    time.Second,
+			Timeout:     5 * time.Second,
+			Retries:     3,
+			StartPeriod: s.healthcheckStartPeriod(),
+		}
+	}
+	for _, mutate := range s.containerConfigMutators {
+		mutate(containerConfig)
+	}
+	return containerConfig
+}
+
+func (s *Stack) hostConfig() *containertypes.HostConfig {
+	hostConfig := &containertypes.HostConfig{
+		PortBindings: s.pm,
+		Mounts:       s.getVolumeMounts(),
+		AutoRemove:   true,
+		CapAdd:       s.capAdd,
+		DNS:          s.dns,
+		Resources: containertypes.Resources{
+			Ulimits: s.ulimits,
+		},
+	}
+	if s.readOnlyRootFS {
+		hostConfig.ReadonlyRootfs = true
+		hostConfig.Tmpfs = map[string]string{}
+		for _, path := range writablePaths {
+			hostConfig.Tmpfs[path] = ""
+		}
+	}
+	for _, mutate := range s.hostConfigMutators {
+		mutate(hostConfig)
+	}
+	return hostConfig
+}
 
-	logLineCheck := s.initCompleteLogLine
-	if logLineCheck == "" {
-		logLineCheck = "Ready."
+// checkMountSources fails for bind mounts whose host path does not exist, which Docker otherwise
+// reports with an obscure error. The docker socket is checked on its own
+func (s *Stack) checkMountSources() error {
+	targets := make([]string, 0, len(s.volumeMounts))
+	for target := range s.volumeMounts {
+		targets = append(targets, target)
 	}
+	sort.Strings(targets)
 
-	return strings.Contains(string(logContent), logLineCheck)
+	for _, target := range targets {
+		source := s.volumeMounts[target]
+		if _, err := os.Stat(source); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("localstack: mount source %s does not exist", source)
+			}
+			return fmt.Errorf("localstack: mount source %s: %w", source, err)
+		}
+	}
+	return nil
 }
 
 func (s *Stack) getVolumeMounts() []mount.Mount {
@@ -236,43 +1429,206 @@ func (s *Stack) getVolumeMounts() []mount.Mount {
 			ReadOnly: true,
 		})
 	}
-	mounts = append(mounts, mount.Mount{
-		Type:   mount.TypeBind,
-		Source: "/var/run/docker.sock",
-		Target: "/var/run/docker.sock",
-	})
+	if !s.noDockerSocket {
+		mounts = append(mounts, mount.Mount{
+			Type:   mount.TypeBind,
+			Source: dockerSocketPath,
+			Target: dockerSocketPath,
+		})
+	}
+	// the mounts come from a map, sorting keeps Options and the signature of StartShared stable
+	sort.Slice(mounts, func(i, j int) bool { return mounts[i].Target < mounts[j].Target })
 	return mounts
 }
 
-func (s *Stack) isFunctional() bool {
-	if !s.started {
-		return false
+func (s *Stack) getEnv() []string {
+	vars := copyMap(s.env)
+	if s.localstackHostDomain {
+		if bindings := s.pm[nat.Port(FixedPort)]; len(bindings) > 0 && bindings[0].HostPort != "" {
+			if _, ok := vars["LOCALSTACK_HOST"]; !ok {
+				if vars == nil {
+					vars = make(map[string]string)
+				}
+				vars["LOCALSTACK_HOST"] = localstackHostDomain + ":" + bindings[0].HostPort
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(vars))
+	for key := range vars {
+		keys = append(keys, key)
 	}
+	sort.Strings(keys)
 
+	env := make([]string, 0, len(keys))
+	for _, key := range keys {
+		env = append(env, key+"="+vars[key])
+	}
+	return env
+}
+
+func (s *Stack) isFunctional() bool {
+	return s.started && s.probe() == nil
+}
+
+// probe exercises the probe service with a throwaway resource, named uniquely so it cannot clash
+// with resources of the tests
+func (s *Stack) probe() error {
 	cfg, err := s.createTestConfig()
 	if err != nil {
-		return false
+		return err
 	}
-	api := sqs.NewFromConfig(cfg)
-	queueUrl, err := api.CreateQueue(s.ctx, &sqs.CreateQueueInput{QueueName: aws.String("test-queue")})
-	if err != nil || queueUrl.QueueUrl == nil {
-		return false
+	name := probeName()
+
+	switch s.probeService() {
+	case "s3":
+		api := s3.NewFromConfig(cfg, func(o *s3.Options) { o.UsePathStyle = true })
+		if _, err := api.CreateBucket(s.ctx, s.createBucketInput(name)); err != nil {
+			return err
+		}
+		_, _ = api.DeleteBucket(s.ctx, &s3.DeleteBucketInput{Bucket: aws.String(name)})
+	case "dynamodb":
+		if _, err := dynamodb.NewFromConfig(cfg).ListTables(s.ctx, &dynamodb.ListTablesInput{}); err != nil {
+			return err
+		}
+	default:
+		api := sqs.NewFromConfig(cfg)
+		queueUrl, err := api.CreateQueue(s.ctx, &sqs.CreateQueueInput{QueueName: aws.String(name)})
+		if err != nil {
+			return err
+		}
+		_, _ = api.DeleteQueue(s.ctx, &sqs.DeleteQueueInput{QueueUrl: queueUrl.QueueUrl})
 	}
-	_, _ = api.DeleteQueue(s.ctx, &sqs.DeleteQueueInput{QueueUrl: queueUrl.QueueUrl})
-	return true
+	return nil
+}
+
+// probeName returns a name valid for queues and buckets that no test uses
+func probeName() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return "localstack-probe-" + hex.EncodeToString(b)
+}
+
+// Config returns an AWS config whose clients talk to the stack. It is built on first use and
+// reused until the stack is stopped or restarted
+func (s *Stack) Config() (aws.Config, error) {
+	return s.createTestConfig()
+}
+
+// probeService returns the service isFunctional probes: the one set by WithSmokeTestService, else
+// the first probeable service enabled by WithServices
+func (s *Stack) probeService() string {
+	if s.smokeTestService != "" {
+		return s.smokeTestService
+	}
+	for _, service := range s.services {
+		switch service {
+		case "sqs", "s3", "dynamodb":
+			return service
+		}
+	}
+	return "sqs"
 }
 
 func (s *Stack) createTestConfig() (aws.Config, error) {
-	return config.LoadDefaultConfig(s.ctx,
-		config.WithRegion("us-east-1"),
-		config.WithEndpointResolverWithOptions(aws.EndpointResolverWithOptionsFunc(func(_, _ string, _ ...interface{}) (aws.Endpoint, error) {
+	if !s.started {
+		return aws.Config{}, ErrNotStarted
+	}
+	s.cfgMu.Lock()
+	defer s.cfgMu.Unlock()
+	if s.draining {
+		return aws.Config{}, ErrStopping
+	}
+	if s.cfg != nil {
+		return *s.cfg, nil
+	}
+
+	opts := []func(*config.LoadOptions) error{
+		config.WithRegion(s.region),
+	}
+	if !s.defaultCredentialChain {
+		opts = append(opts, config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(s.StaticCredentials())))
+	}
+	if !s.useBaseEndpoint {
+		opts = append(opts, config.WithEndpointResolverWithOptions(aws.EndpointResolverWithOptionsFunc(func(_, _ string, _ ...interface{}) (aws.Endpoint, error) {
 			return aws.Endpoint{
-				PartitionID:       "aws",
+				PartitionID:       s.partition,
 				URL:               s.EndpointURL(),
-				SigningRegion:     "us-east-1",
+				SigningRegion:     s.region,
 				HostnameImmutable: true,
 			}, nil
-		})),
-		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider("dummy", "dummy", "dummy")),
-	)
+		})))
+	}
+	if s.retryMode != "" {
+		opts = append(opts, config.WithRetryMode(s.retryMode))
+	}
+	if s.retryMaxAttempts > 0 {
+		opts = append(opts, config.WithRetryMaxAttempts(s.retryMaxAttempts))
+	}
+	var next aws.HTTPClient = awshttp.NewBuildableClient().WithTransportOptions(s.configureTransport)
+	if s.sdkHTTPClient != nil {
+		next = s.sdkHTTPClient
+	}
+	opts = append(opts, config.WithHTTPClient(trackingClient{next: next, inFlight: &s.inFlight}))
+	cfg, err := config.LoadDefaultConfig(s.ctx, opts...)
+	if err != nil {
+		return aws.Config{}, err
+	}
+	if s.useBaseEndpoint {
+		cfg.BaseEndpoint = aws.String(s.EndpointURL())
+	}
+	s.cfg = &cfg
+	return cfg, nil
+}
+
+// accessKeyID returns the account id set by WithAccountID, LocalStack derives the account from a
+// 12-digit access key
+func (s *Stack) accessKeyID() string {
+	if s.accountID != "" {
+		return s.accountID
+	}
+	return "dummy"
+}
+
+func (s *Stack) resetConfig() {
+	s.cfgMu.Lock()
+	defer s.cfgMu.Unlock()
+	s.cfg = nil
+	s.edition = ""
+	if s.rawClient != nil {
+		s.rawClient.CloseIdleConnections()
+		s.rawClient = nil
+	}
+	s.draining = false
+}
+
+// Drain stops the stack gracefully: Config fails with ErrStopping from now on, requests already
+// sent by clients of the stack get up to the drain timeout (see WithShutdownDrainTimeout) or until
+// ctx is done to complete, then the container is stopped
+func (s *Stack) Drain(ctx context.Context) error {
+	s.cfgMu.Lock()
+	s.draining = true
+	s.cfgMu.Unlock()
+
+	deadline := time.Now().Add(s.drainTimeout)
+	for atomic.LoadInt64(&s.inFlight) > 0 && time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return s.Stop()
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	return s.Stop()
+}
+
+// trackingClient counts the requests in flight through the SDK clients of the stack
+type trackingClient struct {
+	next     aws.HTTPClient
+	inFlight *int64
+}
+
+func (c trackingClient) Do(req *http.Request) (*http.Response, error) {
+	atomic.AddInt64(c.inFlight, 1)
+	defer atomic.AddInt64(c.inFlight, -1)
+	return c.next.Do(req)
 }
@@ -3,10 +3,23 @@ package localstack
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
 	"github.com/docker/docker/api/types"
+	containertypes "github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/nat"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -48,3 +61,582 @@ func ensureNoLocalStack(t *testing.T) {
 	}
 
 }
+
+func Test_ListResources(t *testing.T) {
+
+	ensureNoLocalStack(t)
+
+	stack := New()
+	require.NoError(t, stack.Start(false))
+	defer func() { _ = stack.Stop() }()
+
+	cfg, err := stack.createTestConfig()
+	require.NoError(t, err)
+	_, err = sqs.NewFromConfig(cfg).CreateQueue(context.Background(), &sqs.CreateQueueInput{QueueName: aws.String("list-queue")})
+	require.NoError(t, err)
+
+	queues, err := stack.ListQueues(context.Background())
+	require.NoError(t, err)
+	require.Len(t, queues, 1)
+	assert.Equal(t, stack.EndpointURL()+"/000000000000/list-queue", queues[0])
+
+	buckets, err := stack.ListBuckets(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, buckets)
+
+	tables, err := stack.ListTables(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, tables)
+}
+
+func Test_ForceRestartReusedStack(t *testing.T) {
+
+	ensureNoLocalStack(t)
+
+	stack := New()
+	require.NoError(t, stack.Start(false, WithReuseExisting()))
+	defer func() { _ = stack.Stop() }()
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, stack.Start(true, WithReuseExisting()))
+		assert.NotEmpty(t, stack.EndpointURL())
+	}
+	assert.True(t, stack.isFunctional())
+}
+
+func Test_FatalLogLine(t *testing.T) {
+
+	_, ok := fatalLogLine("LocalStack version: 3.0.0\nReady.\n")
+	assert.False(t, ok)
+
+	_, ok = fatalLogLine("Starting\nTraceback (most recent call last):\n  File \"x.py\"\n")
+	assert.False(t, ok)
+
+	line, ok := fatalLogLine("2024-01-02T10:00:00.000 ERROR --- [main] localstack.services.infra : Error starting infrastructure: port taken\n")
+	assert.True(t, ok)
+	assert.Equal(t, "2024-01-02T10:00:00.000 ERROR --- [main] localstack.services.infra : Error starting infrastructure: port taken", line)
+}
+
+func Test_LogTail(t *testing.T) {
+
+	assert.Empty(t, logTail(""))
+	assert.Equal(t, ", last log lines:\na\nb", logTail("a\nb\n"))
+
+	var lines []string
+	for i := 0; i < exitLogLines+5; i++ {
+		lines = append(lines, strconv.Itoa(i))
+	}
+	tail := logTail(strings.Join(lines, "\n"))
+	assert.True(t, strings.HasPrefix(tail, ", last log lines:\n5\n"))
+	assert.True(t, strings.HasSuffix(tail, "\n24"))
+}
+
+func Test_FloatingTag(t *testing.T) {
+
+	assert.False(t, floatingTag(LocalStackImage))
+	assert.False(t, floatingTag("localstack/localstack@sha256:abc"))
+	assert.False(t, floatingTag("registry:5000/localstack/localstack:3.0"))
+	assert.True(t, floatingTag("localstack/localstack"))
+	assert.True(t, floatingTag("localstack/localstack:latest"))
+	assert.True(t, floatingTag("registry:5000/localstack/localstack"))
+}
+
+func Test_Clone(t *testing.T) {
+
+	stack := New()
+	WithEnv("SERVICES", "sqs")(stack)
+	WithUlimit("nofile", 1024, 2048)(stack)
+	stack.containerID = "running"
+	stack.started = true
+
+	clone := stack.Clone()
+	WithEnv("SERVICES", "s3")(clone)
+	clone.ulimits[0].Soft = 4096
+
+	assert.False(t, clone.started)
+	assert.Empty(t, clone.containerID)
+	assert.Equal(t, "sqs", stack.env["SERVICES"])
+	assert.Equal(t, "s3", clone.env["SERVICES"])
+	assert.Equal(t, int64(1024), stack.ulimits[0].Soft)
+}
+
+func Test_Options(t *testing.T) {
+
+	stack := New()
+	WithEnv("SERVICES", "sqs")(stack)
+	WithReuseExisting()(stack)
+	stack.volumeMounts = map[string]string{"/b": "/host/b", "/a": "/host/a", "/c": "/host/c"}
+
+	opts := stack.Options()
+	assert.Equal(t, LocalStackImage, opts.Image)
+	assert.Equal(t, "localstack", opts.ContainerName)
+	assert.Equal(t, map[string]string{"SERVICES": "sqs"}, opts.Env)
+	require.Len(t, opts.Mounts, 4)
+	for i, target := range []string{"/a", "/b", "/c", dockerSocketPath} {
+		assert.Equal(t, target, opts.Mounts[i].Target)
+	}
+	assert.Contains(t, opts.PortBindings, nat.Port(FixedPort))
+}
+
+func Test_Plan(t *testing.T) {
+
+	stack := New()
+	WithEnv("SERVICES", "sqs")(stack)
+
+	withPort, err := WithPort("4571", "")
+	require.NoError(t, err)
+	_, err = WithPort("4571/http", "")
+	assert.Error(t, err)
+
+	plan := stack.Plan(WithEnv("DEBUG", "1"), withPort)
+	assert.Equal(t, LocalStackImage, plan.Image)
+	assert.Contains(t, plan.Config.ExposedPorts, nat.Port("4571/tcp"))
+	assert.Contains(t, plan.HostConfig.PortBindings, nat.Port("4571/tcp"))
+
+	stack.pm[nat.Port("4571/tcp")] = []nat.PortBinding{{HostIP: "localhost", HostPort: "49154"}}
+	stack.bindPorts("")
+	assert.Equal(t, nat.PortMap{nat.Port(FixedPort): []nat.PortBinding{{HostIP: "0.0.0.0"}}}, stack.pm)
+	assert.Equal(t, []string{"DEBUG=1", "SERVICES=sqs"}, plan.Config.Env)
+	assert.Contains(t, plan.HostConfig.PortBindings, nat.Port(FixedPort))
+	assert.Equal(t, map[string]string{"SERVICES": "sqs"}, stack.env)
+}
+
+func Test_EnsureImageCancelledDuringPull(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/images/json"):
+			_, _ = w.Write([]byte("[]"))
+		case strings.HasSuffix(r.URL.Path, "/images/create"):
+			_, _ = w.Write([]byte(`{"status":"Pulling fs layer"}` + "\n"))
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cli, err := client.NewClientWithOpts(client.WithHost("tcp://"+server.Listener.Addr().String()), client.WithVersion("1.41"))
+	require.NoError(t, err)
+	stack := New()
+	stack.cli = cli
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	begin := time.Now()
+	err = stack.ensureImage(ctx, LocalStackImage)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(begin), 2*time.Second)
+}
+
+func Test_ForceRestartResetsOptions(t *testing.T) {
+
+	ensureNoLocalStack(t)
+
+	stack := New()
+	require.NoError(t, stack.Start(false, WithEnv("DEBUG", "1"), WithEnv("SERVICES", "sqs")))
+	defer func() { _ = stack.Stop() }()
+
+	require.NoError(t, stack.Start(true))
+	assert.Empty(t, stack.Options().Env)
+
+	require.NoError(t, stack.Start(true, WithEnv("SERVICES", "sqs,s3")))
+	assert.Equal(t, map[string]string{"SERVICES": "sqs,s3"}, stack.Options().Env)
+	assert.True(t, stack.isFunctional())
+}
+
+func Test_EndpointURLBeforePortResolved(t *testing.T) {
+
+	stack := New()
+	assert.Empty(t, stack.EndpointURL())
+
+	stack.containerID = "created"
+	stack.pm[nat.Port(FixedPort)] = []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: ""}}
+	stack.publishEndpoint()
+	assert.Empty(t, stack.EndpointURL())
+
+	stack.pm[nat.Port(FixedPort)] = []nat.PortBinding{{HostIP: "localhost", HostPort: "49153"}}
+	stack.publishEndpoint()
+	assert.Equal(t, "http://localhost:49153", stack.EndpointURL())
+	_, err := stack.EdgePort()
+	assert.ErrorIs(t, err, ErrNotStarted)
+
+	stack.started = true
+	port, err := stack.EdgePort()
+	require.NoError(t, err)
+	assert.Equal(t, 49153, port)
+
+	_, err = stack.MappedPort("4571")
+	assert.Error(t, err)
+	stack.pm[nat.Port("4571/tcp")] = []nat.PortBinding{{HostIP: "localhost", HostPort: "49154"}}
+	mapped, err := stack.MappedPort("4571")
+	require.NoError(t, err)
+	assert.Equal(t, "49154", mapped)
+
+	port, err = Attach("https://localstack.internal").EdgePort()
+	require.NoError(t, err)
+	assert.Equal(t, 443, port)
+}
+
+func Test_ErrNotStarted(t *testing.T) {
+
+	stack := New()
+	ctx := context.Background()
+
+	_, err := stack.Config()
+	assert.ErrorIs(t, err, ErrNotStarted)
+	_, err = stack.ListQueues(ctx)
+	assert.ErrorIs(t, err, ErrNotStarted)
+	_, err = stack.Inspect(ctx)
+	assert.ErrorIs(t, err, ErrNotStarted)
+	_, err = stack.Health(ctx)
+	assert.ErrorIs(t, err, ErrNotStarted)
+	_, err = stack.WatchHealth(ctx)
+	assert.ErrorIs(t, err, ErrNotStarted)
+	assert.ErrorIs(t, stack.Reset(ctx), ErrNotStarted)
+}
+
+func Test_ConfigMutators(t *testing.T) {
+
+	stack := New()
+	WithEnv("SERVICES", "sqs")(stack)
+	WithCapAdd("NET_ADMIN")(stack)
+	WithContainerConfigMutator(func(c *containertypes.Config) {
+		assert.Equal(t, LocalStackImage, c.Image)
+		assert.Equal(t, []string{"SERVICES=sqs"}, c.Env)
+		c.Env = append(c.Env, "DEBUG=1")
+	})(stack)
+	WithHostConfigMutator(func(h *containertypes.HostConfig) {
+		assert.True(t, h.AutoRemove)
+		assert.Equal(t, []string{"NET_ADMIN"}, []string(h.CapAdd))
+		h.Sysctls = map[string]string{"net.core.somaxconn": "1024"}
+	})(stack)
+
+	assert.Equal(t, []string{"SERVICES=sqs", "DEBUG=1"}, stack.containerConfig().Env)
+	assert.Equal(t, "1024", stack.hostConfig().Sysctls["net.core.somaxconn"])
+}
+
+func Test_WithStackEndpoint(t *testing.T) {
+
+	stack := Attach("http://localhost:4566")
+
+	var s3Options s3.Options
+	WithStackEndpoint[s3.Options](stack)(&s3Options)
+	assert.Equal(t, "http://localhost:4566", aws.ToString(s3Options.BaseEndpoint))
+	assert.True(t, s3Options.UsePathStyle)
+
+	var sqsOptions sqs.Options
+	WithStackEndpoint[sqs.Options](stack)(&sqsOptions)
+	assert.Equal(t, "http://localhost:4566", aws.ToString(sqsOptions.BaseEndpoint))
+}
+
+func Test_LocalstackHostDomain(t *testing.T) {
+
+	stack := New()
+	WithLocalstackHostDomain()(stack)
+	assert.True(t, stack.autoPort)
+	assert.Empty(t, stack.getEnv())
+
+	stack.pm[nat.Port(FixedPort)] = []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: "49153"}}
+	assert.Equal(t, []string{"LOCALSTACK_HOST=localhost.localstack.cloud:49153"}, stack.getEnv())
+	assert.Nil(t, stack.env)
+}
+
+func Test_FilterServiceLogs(t *testing.T) {
+
+	logs := "2024-01-02T10:00:00.000  INFO --- [  asgi_gw_0] localstack.request.aws     : AWS sqs.CreateQueue => 200\n" +
+		"2024-01-02T10:00:01.000  INFO --- [  asgi_gw_1] localstack.request.aws     : AWS s3.CreateBucket => 200\n" +
+		"2024-01-02T10:00:02.000 ERROR --- [  asgi_gw_0] l.s.sqs.provider           : failed\n" +
+		"2024-01-02T10:00:03.000 ERROR --- [  asgi_gw_0] localstack.services.sqs.provider : failed\n" +
+		"Traceback (most recent call last):\n" +
+		"2024-01-02T10:00:04.000  INFO --- [  asgi_gw_1] localstack.services.sqsx   : other\n"
+
+	assert.Equal(t, "2024-01-02T10:00:00.000  INFO --- [  asgi_gw_0] localstack.request.aws     : AWS sqs.CreateQueue => 200\n"+
+		"2024-01-02T10:00:02.000 ERROR --- [  asgi_gw_0] l.s.sqs.provider           : failed\n"+
+		"2024-01-02T10:00:03.000 ERROR --- [  asgi_gw_0] localstack.services.sqs.provider : failed\n"+
+		"Traceback (most recent call last):\n", filterServiceLogs(logs, "SQS"))
+	assert.Empty(t, filterServiceLogs(logs, "dynamodb"))
+}
+
+func Test_WithEnvFile(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "localstack.env")
+	require.NoError(t, os.WriteFile(path, []byte("# tuning\nSERVICES=sqs,s3\n\nDEBUG = \"1\"\n"), 0o644))
+
+	withEnvFile, err := WithEnvFile(path)
+	require.NoError(t, err)
+	stack := New()
+	withEnvFile(stack)
+	WithEnv("DEBUG", "0")(stack)
+	assert.Equal(t, map[string]string{"SERVICES": "sqs,s3", "DEBUG": "0"}, stack.env)
+
+	require.NoError(t, os.WriteFile(path, []byte("SERVICES=sqs\nDEBUG\n"), 0o644))
+	_, err = WithEnvFile(path)
+	assert.ErrorContains(t, err, "line 2")
+}
+
+func Test_Builder(t *testing.T) {
+
+	stack, err := NewBuilder().Image("localstack/localstack:3.1").Services("sqs", "s3").Region("eu-west-1").Build()
+	require.NoError(t, err)
+
+	withServices, err := WithServices("sqs", "s3")
+	require.NoError(t, err)
+	expected := New()
+	for _, opt := range []StackOption{WithImage("localstack/localstack:3.1"), withServices, WithRegion("eu-west-1")} {
+		opt(expected)
+	}
+	assert.Equal(t, expected.Options(), stack.Options())
+	assert.Equal(t, "eu-west-1", stack.Region())
+
+	_, err = NewBuilder().Services("not a service").Build()
+	assert.Error(t, err)
+}
+
+func Test_DumpQueueKeepsMessages(t *testing.T) {
+
+	ensureNoLocalStack(t)
+
+	stack := New()
+	require.NoError(t, stack.Start(false))
+	defer func() { _ = stack.Stop() }()
+
+	ctx := context.Background()
+	queueURL, err := stack.EnsureQueue(ctx, "dump-queue")
+	require.NoError(t, err)
+	cfg, err := stack.Config()
+	require.NoError(t, err)
+	api := sqs.NewFromConfig(cfg)
+	_, err = api.SendMessage(ctx, &sqs.SendMessageInput{QueueUrl: aws.String(queueURL), MessageBody: aws.String("hello")})
+	require.NoError(t, err)
+
+	bodies, err := stack.DumpQueue(ctx, queueURL)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"hello"}, bodies)
+
+	out, err := api.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{QueueUrl: aws.String(queueURL)})
+	require.NoError(t, err)
+	require.Len(t, out.Messages, 1)
+	assert.Equal(t, "hello", aws.ToString(out.Messages[0].Body))
+}
+
+func Test_StackSignature(t *testing.T) {
+
+	signatureOf := func(opts ...StackOption) string {
+		stack := New()
+		for _, opt := range opts {
+			opt(stack)
+		}
+		stack.volumeMounts = map[string]string{"/a": "/host/a", "/b": "/host/b", "/c": "/host/c"}
+		sig, err := stackSignature(stack)
+		require.NoError(t, err)
+		return sig
+	}
+
+	first := signatureOf(WithEnv("SERVICES", "sqs"))
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, first, signatureOf(WithEnv("SERVICES", "sqs")))
+	}
+	assert.NotEqual(t, first, signatureOf(WithEnv("SERVICES", "sqs"), WithRegion("eu-west-1")))
+	assert.NotEqual(t, first, signatureOf(WithEnv("SERVICES", "sqs"), WithDefaultCredentialChain()))
+}
+
+func Test_EndpointURLWhileRebinding(t *testing.T) {
+
+	stack := New()
+	stack.containerID = "running"
+	stack.started = true
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			stack.Lock()
+			stack.bindPorts(strconv.Itoa(49000 + i))
+			stack.publishEndpoint()
+			stack.Unlock()
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			assert.Equal(t, "http://0.0.0.0:49199", stack.EndpointURL())
+			return
+		default:
+			_ = stack.EndpointURL()
+		}
+	}
+}
+
+func Test_AutoRestartWhileResolving(t *testing.T) {
+
+	ensureNoLocalStack(t)
+
+	stack := New()
+	require.NoError(t, stack.Start(false, WithAutoRestart(1)))
+	defer func() { _ = stack.Stop() }()
+
+	cfg, err := stack.Config()
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		for ctx.Err() == nil {
+			_, _ = cfg.EndpointResolverWithOptions.ResolveEndpoint("sqs", stack.Region())
+			_ = stack.EndpointURL()
+		}
+	}()
+
+	cont, err := stack.Inspect(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stack.DockerClient().ContainerKill(ctx, cont.ID, "SIGKILL"))
+	require.Eventually(t, func() bool {
+		cont, err := stack.Inspect(ctx)
+		return stack.Restarts() == 1 && err == nil && cont.State.Running
+	}, 2*time.Minute, time.Second)
+}
+
+// fakeDocker serves the Docker API calls of a start, with logs as the TTY log of the container
+type fakeDocker struct {
+	*httptest.Server
+	logs     string
+	edgePort string
+	removed  int32
+	stopped  int32
+}
+
+func newFakeDocker(t *testing.T, logs string) *fakeDocker {
+	fake := &fakeDocker{logs: logs, edgePort: "49153"}
+	fake.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/images/json"):
+			_, _ = fmt.Fprintf(w, `[{"Id": "sha256:img", "RepoTags": [%q]}]`, LocalStackImage)
+		case strings.HasSuffix(r.URL.Path, "/images/"+LocalStackImage+"/json"):
+			_, _ = w.Write([]byte(`{"Id": "sha256:img", "Os": "linux", "Architecture": "amd64"}`))
+		case strings.HasSuffix(r.URL.Path, "/version"):
+			_, _ = w.Write([]byte(`{"Os": "linux", "Arch": "amd64"}`))
+		case strings.HasSuffix(r.URL.Path, "/containers/create"):
+			_, _ = w.Write([]byte(`{"Id": "ls"}`))
+		case strings.HasSuffix(r.URL.Path, "/containers/ls/start"):
+			w.WriteHeader(http.StatusNoContent)
+		case strings.HasSuffix(r.URL.Path, "/containers/ls/stop"):
+			atomic.AddInt32(&fake.stopped, 1)
+			w.WriteHeader(http.StatusNoContent)
+		case strings.HasSuffix(r.URL.Path, "/containers/ls/json"):
+			_, _ = fmt.Fprintf(w, `{"Id": "ls", "State": {"Status": "running", "Running": true},
+				"NetworkSettings": {"Ports": {"4566/tcp": [{"HostIp": "0.0.0.0", "HostPort": %q}]}}}`, fake.edgePort)
+		case strings.HasSuffix(r.URL.Path, "/containers/ls/logs"):
+			_, _ = w.Write([]byte(fake.logs))
+		case r.Method == http.MethodDelete && strings.HasSuffix(r.URL.Path, "/containers/ls"):
+			atomic.AddInt32(&fake.removed, 1)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(fake.Close)
+	return fake
+}
+
+func (f *fakeDocker) stack(t *testing.T) *Stack {
+	cli, err := client.NewClientWithOpts(client.WithHost("tcp://"+f.Listener.Addr().String()), client.WithVersion("1.41"))
+	require.NoError(t, err)
+	stack := New()
+	stack.cli = cli
+	stack.readyPollInterval = 10 * time.Millisecond
+	WithoutDockerSocket()(stack)
+	return stack
+}
+
+func Test_StartCancelledDuringInitRemovesContainer(t *testing.T) {
+
+	fake := newFakeDocker(t, "Starting\r\n")
+	stack := fake.stack(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	err := stack.Start(false, WithContext(ctx))
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fake.removed))
+	assert.Empty(t, stack.EndpointURL())
+}
+
+func Test_FailedInitRemovesContainer(t *testing.T) {
+
+	fake := newFakeDocker(t, "2024-01-02T10:00:00.000 ERROR --- [main] localstack.services.infra : Error starting infrastructure\r\n")
+	err := fake.stack(t).Start(false)
+	assert.ErrorContains(t, err, "container failed during init")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fake.removed))
+
+	fake = newFakeDocker(t, "Starting\r\n")
+	err = fake.stack(t).Start(false, WithInitTimeout(50*time.Millisecond))
+	assert.ErrorIs(t, err, ErrInitTimeout)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fake.removed))
+}
+
+func Test_EnsureImagePullsOtherPlatform(t *testing.T) {
+
+	var pulls int32
+	fake := newFakeDocker(t, "")
+	next := fake.Config.Handler
+	fake.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/images/create") {
+			atomic.AddInt32(&pulls, 1)
+			assert.Equal(t, "linux/arm64", r.URL.Query().Get("platform"))
+			_, _ = w.Write([]byte(`{"status":"Downloaded newer image"}` + "\n"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+
+	stack := fake.stack(t)
+	require.NoError(t, stack.ensureImage(context.Background(), LocalStackImage))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&pulls))
+
+	withPlatform, err := WithPlatform("linux/amd64")
+	require.NoError(t, err)
+	withPlatform(stack)
+	require.NoError(t, stack.ensureImage(context.Background(), LocalStackImage))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&pulls))
+
+	withPlatform, err = WithPlatform("linux/arm64")
+	require.NoError(t, err)
+	withPlatform(stack)
+	require.NoError(t, stack.ensureImage(context.Background(), LocalStackImage))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&pulls))
+}
+
+func Test_CreateBucketInput(t *testing.T) {
+
+	input := New().createBucketInput("bucket")
+	assert.Equal(t, "bucket", aws.ToString(input.Bucket))
+	assert.Nil(t, input.CreateBucketConfiguration)
+
+	stack := New()
+	WithRegion("eu-west-1")(stack)
+	input = stack.createBucketInput("bucket")
+	require.NotNil(t, input.CreateBucketConfiguration)
+	assert.Equal(t, "eu-west-1", string(input.CreateBucketConfiguration.LocationConstraint))
+}
+
+func Test_ForceRestartIgnoresEarlierContext(t *testing.T) {
+
+	fake := newFakeDocker(t, "Ready.\r\n")
+	stack := fake.stack(t)
+
+	first, cancelFirst := context.WithCancel(context.Background())
+	require.NoError(t, stack.Start(false, WithContext(first), WithoutDockerSocket()))
+	second, cancelSecond := context.WithCancel(context.Background())
+	require.NoError(t, stack.Start(true, WithContext(second), WithoutDockerSocket()))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fake.stopped))
+
+	cancelFirst()
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fake.stopped))
+	assert.NotEmpty(t, stack.EndpointURL())
+
+	cancelSecond()
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&fake.stopped) == 2 }, 2*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,50 @@
+package localstack
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// logEntryStart matches the timestamp LocalStack starts every log entry with, lines without it
+// continue the previous entry, e.g. a traceback
+var logEntryStart = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}`)
+
+// ServiceLogs returns the log entries of the current run that belong to service, e.g. "sqs": the
+// ones logged by its provider (localstack.services.sqs...) and the request log lines of its API
+// calls (AWS sqs.CreateQueue => 200)
+func (s *Stack) ServiceLogs(ctx context.Context, service string) (string, error) {
+	s.RLock()
+	defer s.RUnlock()
+	if !s.started || s.containerID == "" {
+		return "", ErrNotStarted
+	}
+
+	logs, err := s.containerLogs(ctx)
+	if err != nil {
+		return "", fmt.Errorf("localstack: reading container logs: %w", err)
+	}
+	return filterServiceLogs(logs, service), nil
+}
+
+func filterServiceLogs(logs, service string) string {
+	service = regexp.QuoteMeta(strings.ToLower(service))
+	// long logger names are abbreviated, localstack.services.sqs.provider becomes l.s.sqs.provider
+	pattern := regexp.MustCompile(`\b(?:services|s)\.` + service + `\b|\bAWS ` + service + `\.`)
+
+	var b strings.Builder
+	keep := false
+	for _, line := range strings.SplitAfter(logs, "\n") {
+		if line == "" {
+			continue
+		}
+		if logEntryStart.MatchString(line) {
+			keep = pattern.MatchString(line)
+		}
+		if keep {
+			b.WriteString(line)
+		}
+	}
+	return b.String()
+}
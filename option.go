@@ -1,13 +1,30 @@
 package localstack
 
 import (
+	"bufio"
 	"context"
 	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	containertypes "github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+	"github.com/docker/go-units"
 )
 
 type StackOption func(s *Stack)
 
+// InitScriptVarPrefix is prepended to every variable passed through WithInitScriptVars
+const InitScriptVarPrefix = "INIT_"
+
 // WithInitScriptMount configures the instance with init scripts and waits for a specific line from
 // the script to show as ready to continue
 func WithInitScriptMount(initScriptDirPath string, completeLogLine string) (StackOption, error) {
@@ -35,15 +52,37 @@ func WithContext(ctx context.Context) StackOption {
 	}
 }
 
-func WithInitTimeout(timeout int) StackOption {
+// WithInitTimeout bounds how long Start waits for LocalStack to become ready, no limit by default
+func WithInitTimeout(timeout time.Duration) StackOption {
 	return func(s *Stack) {
 		s.initTimeout = timeout
 	}
 }
 
+// WithInitTimeoutSeconds is WithInitTimeout with the timeout given in seconds
+//
+// Deprecated: use WithInitTimeout(time.Duration(seconds) * time.Second)
+func WithInitTimeoutSeconds(seconds int) StackOption {
+	return WithInitTimeout(time.Duration(seconds) * time.Second)
+}
+
+// WithReuseExisting names the container "localstack" and, if a container of that name already
+// exists, uses it instead of creating one
 func WithReuseExisting() StackOption {
 	return func(s *Stack) {
 		s.reuseExisting = true
+		s.replaceExisting = false
+		s.containerName = "localstack"
+	}
+}
+
+// WithReplaceExisting names the container "localstack" and, if a container of that name already
+// exists, e.g. left over from a crashed run, force-removes it and creates a fresh one. Unlike
+// WithReuseExisting no state of the old container survives
+func WithReplaceExisting() StackOption {
+	return func(s *Stack) {
+		s.replaceExisting = true
+		s.reuseExisting = false
 		s.containerName = "localstack"
 	}
 }
@@ -53,3 +92,596 @@ func WithNotInitWait() StackOption {
 		s.waitForInit = false
 	}
 }
+
+// WithEnv sets an environment variable on the container, later calls override earlier ones
+func WithEnv(key, value string) StackOption {
+	return func(s *Stack) {
+		if s.env == nil {
+			s.env = make(map[string]string)
+		}
+		s.env[key] = value
+	}
+}
+
+// WithInitScriptVars exposes variables to the scripts mounted with WithInitScriptMount. The scripts
+// inherit the container environment, so this is sugar over WithEnv with every key prefixed by
+// InitScriptVarPrefix, e.g. "BUCKET" is read as $INIT_BUCKET from the script
+func WithInitScriptVars(vars map[string]string) StackOption {
+	return func(s *Stack) {
+		for key, value := range vars {
+			WithEnv(InitScriptVarPrefix+key, value)(s)
+		}
+	}
+}
+
+// WithTLS serves the edge over HTTPS and makes EndpointURL return an https:// URL. LocalStack uses
+// a self-signed certificate, so the clients configured by the stack skip certificate verification
+// (InsecureSkipVerify); clients built elsewhere have to do the same or trust the certificate
+func WithTLS() StackOption {
+	return func(s *Stack) {
+		s.useTLS = true
+		WithEnv("USE_SSL", "1")(s)
+	}
+}
+
+// WithUlimit sets a ulimit on the container, e.g. raising "nofile" for Lambda-heavy workloads
+func WithUlimit(name string, soft, hard int64) StackOption {
+	return func(s *Stack) {
+		s.ulimits = append(s.ulimits, &units.Ulimit{Name: name, Soft: soft, Hard: hard})
+	}
+}
+
+// WithAutoPort selects a free host port for the edge before creating the container instead of
+// leaving the choice to Docker, retrying with a new port if it was taken in the meantime
+func WithAutoPort() StackOption {
+	return func(s *Stack) {
+		s.autoPort = true
+	}
+}
+
+// WithReadyPollInterval sets how long the init wait and WatchHealth initially sleep between
+// checks, backing off from there up to 2 seconds. Defaults to 500ms
+func WithReadyPollInterval(d time.Duration) StackOption {
+	return func(s *Stack) {
+		s.readyPollInterval = d
+	}
+}
+
+// WithSmokeTestService selects the service used to check the stack is functional, one of "sqs"
+// (the default), "s3" or "dynamodb"
+func WithSmokeTestService(service string) (StackOption, error) {
+	switch service {
+	case "sqs", "s3", "dynamodb":
+	default:
+		return nil, fmt.Errorf("localstack: unsupported smoke test service %q", service)
+	}
+
+	return func(s *Stack) {
+		s.smokeTestService = service
+	}, nil
+}
+
+// WithContainerUser runs the container as the given user ("uid", "uid:gid" or a name), e.g. to
+// match the ownership of bind-mounted host directories. Under rootless Docker the ids are mapped
+// into the daemon's user namespace, so the container's root already is the invoking host user
+func WithContainerUser(user string) StackOption {
+	return func(s *Stack) {
+		s.containerUser = user
+	}
+}
+
+// WithCapAdd adds Linux capabilities to the container, e.g. "NET_ADMIN" for networking features
+func WithCapAdd(caps ...string) StackOption {
+	return func(s *Stack) {
+		s.capAdd = append(s.capAdd, caps...)
+	}
+}
+
+// WithoutDockerSocket skips mounting the host Docker socket, which LocalStack only needs to run
+// Lambda functions
+func WithoutDockerSocket() StackOption {
+	return func(s *Stack) {
+		s.noDockerSocket = true
+	}
+}
+
+// WithPlatform pins the image platform used for pulling and creating the container, e.g.
+// "linux/arm64" for native Apple Silicon or "linux/amd64" for parity with CI
+func WithPlatform(platform string) (StackOption, error) {
+	parts := strings.Split(platform, "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("localstack: invalid platform %q, expected os/arch[/variant]", platform)
+	}
+
+	return func(s *Stack) {
+		s.platform = platform
+	}, nil
+}
+
+// WithTimingCallback reports how long each startup phase took, called with "pull", "create",
+// "start" and "init" as the phases complete
+func WithTimingCallback(callback func(phase string, d time.Duration)) StackOption {
+	return func(s *Stack) {
+		s.timingCallback = callback
+	}
+}
+
+// WithLogOutput sets where the default text logger of the stack writes its warnings and errors,
+// defaults to os.Stdout. It has no effect together with WithLogger
+func WithLogOutput(w io.Writer) StackOption {
+	return func(s *Stack) {
+		s.defaultLogger = newDefaultLogger(w)
+	}
+}
+
+// WithPreCreateHook runs hook right before the container is created, e.g. to prepare a mount
+// directory. An error aborts Start
+func WithPreCreateHook(hook func() error) StackOption {
+	return func(s *Stack) {
+		s.preCreateHook = hook
+	}
+}
+
+// WithPostStopHook runs hook after the container was stopped successfully, e.g. to collect artifacts
+func WithPostStopHook(hook func()) StackOption {
+	return func(s *Stack) {
+		s.postStopHook = hook
+	}
+}
+
+// WithShutdownDrainTimeout sets how long Drain waits for in-flight requests before stopping the
+// container, defaults to 5 seconds
+func WithShutdownDrainTimeout(d time.Duration) StackOption {
+	return func(s *Stack) {
+		s.drainTimeout = d
+	}
+}
+
+// WithReadyLogLines waits until every one of lines was logged, in addition to the line given to
+// WithInitScriptMount. Setting it replaces the default "Ready." line, so include it when needed
+func WithReadyLogLines(lines []string) StackOption {
+	return func(s *Stack) {
+		s.readyLogLines = append(s.readyLogLines, lines...)
+	}
+}
+
+// WithClientRetry sets the retry mode and maximum attempts of the clients built from Config, e.g.
+// aws.RetryModeStandard with a low cap to fail fast. A zero value keeps the SDK default
+func WithClientRetry(mode aws.RetryMode, maxAttempts int) StackOption {
+	return func(s *Stack) {
+		s.retryMode = mode
+		s.retryMaxAttempts = maxAttempts
+	}
+}
+
+// WithEndpointHost sets the host EndpointURL, Config and the health checks use to reach the edge
+// instead of localhost, e.g. the Docker machine IP under WSL2 or with a remote daemon
+func WithEndpointHost(host string) StackOption {
+	return func(s *Stack) {
+		s.endpointHost = host
+	}
+}
+
+var serviceNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// WithServices limits LocalStack to the given services (the SERVICES setting), e.g. "sqs", "s3"
+func WithServices(services ...string) (StackOption, error) {
+	if len(services) == 0 {
+		return nil, fmt.Errorf("localstack: at least one service is required")
+	}
+	for _, service := range services {
+		if !serviceNamePattern.MatchString(service) {
+			return nil, fmt.Errorf("localstack: invalid service name %q", service)
+		}
+	}
+
+	return func(s *Stack) {
+		s.services = append([]string(nil), services...)
+		WithEnv("SERVICES", strings.Join(services, ","))(s)
+	}, nil
+}
+
+// WithServicesFromEnv applies WithServices with the comma-separated list read from the host
+// environment variable varName. An empty or unset variable keeps all services enabled
+func WithServicesFromEnv(varName string) (StackOption, error) {
+	var services []string
+	for _, service := range strings.Split(os.Getenv(varName), ",") {
+		if service = strings.TrimSpace(service); service != "" {
+			services = append(services, service)
+		}
+	}
+	if len(services) == 0 {
+		return func(s *Stack) {}, nil
+	}
+	return WithServices(services...)
+}
+
+// WithStartupOutput mirrors the container log to w while Start waits for LocalStack to become
+// ready, like docker logs -f. Use WithLogWriter to keep streaming after startup
+func WithStartupOutput(w io.Writer) StackOption {
+	return func(s *Stack) {
+		s.startupOutput = w
+	}
+}
+
+// WithLogWriter streams the container log to w for the whole lifetime of the container
+func WithLogWriter(w io.Writer) StackOption {
+	return func(s *Stack) {
+		s.logWriter = w
+	}
+}
+
+// WithReadyFile waits until containerPath exists inside the container instead of the default
+// "Ready." log line, e.g. a sentinel file touched by the last script of WithInitScriptMount. Log
+// lines configured explicitly are still awaited as well
+func WithReadyFile(containerPath string) StackOption {
+	return func(s *Stack) {
+		s.readyFile = containerPath
+	}
+}
+
+// WithLambdaCodeMount makes function code in hostDir deployable from local paths. The directory
+// is mounted at the same path inside the container, since LocalStack hands that path to the Docker
+// daemon when mounting it into the Lambda containers, which requires the docker socket mount
+func WithLambdaCodeMount(hostDir string) (StackOption, error) {
+	hostDirAbs, err := filepath.Abs(hostDir)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(hostDirAbs); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("localstack: lambda code directory %s does not exist", hostDirAbs)
+	}
+
+	return func(s *Stack) {
+		if s.volumeMounts == nil {
+			s.volumeMounts = make(map[string]string)
+		}
+		s.volumeMounts[hostDirAbs] = hostDirAbs
+		s.lambdaCodeDir = hostDirAbs
+		WithEnv("LAMBDA_REMOTE_DOCKER", "0")(s)
+	}, nil
+}
+
+// WithRegion sets the region of the clients built from Config, defaults to "us-east-1"
+func WithRegion(region string) StackOption {
+	return func(s *Stack) {
+		s.region = region
+	}
+}
+
+// WithPartition sets the partition the endpoint resolver reports, e.g. "aws-us-gov" or "aws-cn"
+// together with a matching WithRegion, to test partition-aware ARN handling. Defaults to "aws"
+func WithPartition(partitionID string) StackOption {
+	return func(s *Stack) {
+		s.partition = partitionID
+	}
+}
+
+// WithHostConfigMutator lets mutate change the Docker HostConfig right before the container is
+// created, after the stack applied its own settings, e.g. for sysctls, devices or security options.
+// Overriding the port bindings, mounts or AutoRemove set by the stack is at the caller's own risk
+func WithHostConfigMutator(mutate func(*containertypes.HostConfig)) StackOption {
+	return func(s *Stack) {
+		s.hostConfigMutators = append(s.hostConfigMutators, mutate)
+	}
+}
+
+// WithContainerConfigMutator lets mutate change the Docker container Config right before the
+// container is created, after the stack set image, TTY and env, e.g. for an entrypoint override or
+// a healthcheck. Overriding the settings of the stack is at the caller's own risk
+func WithContainerConfigMutator(mutate func(*containertypes.Config)) StackOption {
+	return func(s *Stack) {
+		s.containerConfigMutators = append(s.containerConfigMutators, mutate)
+	}
+}
+
+// WithDockerHealthcheck defines a Docker HEALTHCHECK probing the LocalStack health endpoint and
+// waits for the container to turn healthy instead of scraping the logs, which also makes the state
+// visible in docker ps
+func WithDockerHealthcheck() StackOption {
+	return func(s *Stack) {
+		s.dockerHealthcheck = true
+	}
+}
+
+// WithLogger routes the diagnostics of the stack (pulling, starting, ready, stopping) through
+// logger, at info level for lifecycle events and debug level for details
+func WithLogger(logger *slog.Logger) StackOption {
+	return func(s *Stack) {
+		s.logger = logger
+	}
+}
+
+// WithoutAutoStopOnContext keeps the container running when the context set by WithContext is
+// done, the caller then has to call Stop. The context still bounds the image pull and startup
+func WithoutAutoStopOnContext() StackOption {
+	return func(s *Stack) {
+		s.noAutoStop = true
+	}
+}
+
+// WithImagePullTimeout bounds pulling the image when it is not present locally, separately from
+// WithInitTimeout. Defaults to no timeout
+func WithImagePullTimeout(d time.Duration) StackOption {
+	return func(s *Stack) {
+		s.pullTimeout = d
+	}
+}
+
+var accountIDPattern = regexp.MustCompile(`^[0-9]{12}$`)
+
+// WithAccountID makes LocalStack use the 12-digit account id instead of "000000000000". The
+// clients configured by the stack sign with it as access key, which LocalStack maps to the
+// account, and TEST_AWS_ACCOUNT_ID sets it for other credentials
+func WithAccountID(id string) (StackOption, error) {
+	if !accountIDPattern.MatchString(id) {
+		return nil, fmt.Errorf("localstack: invalid account id %q, expected 12 digits", id)
+	}
+
+	return func(s *Stack) {
+		s.accountID = id
+		WithEnv("TEST_AWS_ACCOUNT_ID", id)(s)
+	}, nil
+}
+
+// WithHTTPClient makes the clients built from Config send their requests through client, e.g. to
+// record traffic or inject faults. The client must still reach EndpointURL, and it replaces the
+// default transport, so WithTLS's certificate skip and the proxy bypass have to be set up on it
+func WithHTTPClient(client *http.Client) StackOption {
+	return func(s *Stack) {
+		s.sdkHTTPClient = client
+	}
+}
+
+// WithStopSignal sets the signal Stop sends to the container, e.g. "SIGINT", instead of the one
+// configured by the image (SIGTERM for LocalStack). The container is killed if it has not exited
+// within the stop timeout of 10 seconds after the signal, so a flush on shutdown has to fit in it
+func WithStopSignal(sig string) StackOption {
+	return func(s *Stack) {
+		s.stopSignal = sig
+	}
+}
+
+// WithIgnoreServicesInReadiness makes readiness also require the health endpoint to report every
+// service usable except the given ones, so a service stuck initializing does not block tests that
+// do not use it. A managed stack checks the health endpoint once its readiness strategy is
+// satisfied, an attached one or a stack whose logs are unavailable only has the health endpoint
+func WithIgnoreServicesInReadiness(services ...string) StackOption {
+	return func(s *Stack) {
+		if s.readinessIgnored == nil {
+			s.readinessIgnored = make(map[string]bool)
+		}
+		for _, service := range services {
+			s.readinessIgnored[service] = true
+		}
+	}
+}
+
+// WithImage runs another image than LocalStackImage, e.g. a newer LocalStack version or a mirror.
+// A tag of "latest" or no tag logs a warning, see WithAllowLatest
+func WithImage(image string) StackOption {
+	return func(s *Stack) {
+		s.image = image
+	}
+}
+
+// WithAllowLatest acknowledges a floating image tag and suppresses the warning about it
+func WithAllowLatest() StackOption {
+	return func(s *Stack) {
+		s.allowLatest = true
+	}
+}
+
+// WithLocalstackHostDomain sets LOCALSTACK_HOST to localhost.localstack.cloud with the mapped edge
+// port, so the hostnames LocalStack generates (queue URLs, S3 virtual-host addresses) resolve to
+// the host and reach the edge. It implies WithAutoPort, the port has to be known before the
+// container is created
+func WithLocalstackHostDomain() StackOption {
+	return func(s *Stack) {
+		s.localstackHostDomain = true
+		s.autoPort = true
+	}
+}
+
+// WithHookTimeout bounds each lifecycle hook (WithPreCreateHook, WithPostStopHook), a hook that
+// does not return in time fails Start or Stop with an error naming it. Defaults to no timeout
+func WithHookTimeout(d time.Duration) StackOption {
+	return func(s *Stack) {
+		s.hookTimeout = d
+	}
+}
+
+// WithBindIP publishes the edge port on the given host address only, e.g. "127.0.0.1" to keep it
+// off the network on shared CI runners, and makes EndpointURL use it. Defaults to "0.0.0.0", all
+// interfaces; prefer loopback in new code, the default is kept for compatibility
+func WithBindIP(ip string) (StackOption, error) {
+	if net.ParseIP(ip) == nil {
+		return nil, fmt.Errorf("localstack: invalid bind ip %q", ip)
+	}
+
+	return func(s *Stack) {
+		s.bindIP = ip
+	}, nil
+}
+
+// WithCaptureLogsOnStop writes the complete container log, including the shutdown, to w when the
+// stack is stopped, before the container is auto-removed, e.g. to keep it as a CI artifact
+func WithCaptureLogsOnStop(w io.Writer) StackOption {
+	return func(s *Stack) {
+		s.captureLogs = w
+	}
+}
+
+// WithDNS sets the DNS servers of the container, e.g. an internal resolver on isolated CI
+// networks. For fully offline runs also pass WithEnv("SKIP_SSL_CERT_DOWNLOAD", "1"). Defaults to
+// the servers of the daemon
+func WithDNS(servers ...string) StackOption {
+	return func(s *Stack) {
+		s.dns = append(s.dns, servers...)
+	}
+}
+
+// WithFunctionalProbe makes Start exercise the smoke test service (see WithSmokeTestService) once
+// LocalStack is ready, creating and deleting a uniquely named throwaway resource, and fail if that
+// does not work. Off by default, the probe has side effects
+func WithFunctionalProbe() StackOption {
+	return func(s *Stack) {
+		s.functionalProbe = true
+	}
+}
+
+// WithCmd overrides the command of the image, e.g. the arguments of a wrapper entrypoint. The edge
+// port is still published and readiness still waits for the ready log line, so a custom command
+// has to end up running LocalStack or be combined with WithReadyLogLines or WithNotInitWait
+func WithCmd(args ...string) StackOption {
+	return func(s *Stack) {
+		s.cmd = args
+	}
+}
+
+// WithEntrypoint overrides the entrypoint of the image, e.g. with a wrapper script that prepares
+// the container and then execs LocalStack. Readiness works as described at WithCmd
+func WithEntrypoint(args ...string) StackOption {
+	return func(s *Stack) {
+		s.entrypoint = args
+	}
+}
+
+// WithUseBaseEndpoint points the clients built from Config at the stack through aws.Config's
+// BaseEndpoint instead of the deprecated custom endpoint resolver. S3 clients then need
+// UsePathStyle, and the partition follows the region, WithPartition has no effect
+func WithUseBaseEndpoint() StackOption {
+	return func(s *Stack) {
+		s.useBaseEndpoint = true
+	}
+}
+
+// WithOnReadyTimeout decides what Start does when the WithInitTimeout budget is exceeded: policy
+// runs with the stack already usable, e.g. to dump its logs, and returning nil lets Start succeed
+// anyway while an error stops the container and is returned. Without it Start returns
+// ErrInitTimeout
+func WithOnReadyTimeout(policy func(s *Stack) error) StackOption {
+	return func(s *Stack) {
+		s.onReadyTimeout = policy
+	}
+}
+
+// WithReadyScript considers the stack ready once script, run with sh -c inside the container at
+// every readiness check, exits with 0, e.g.
+// "curl -sf localhost:4566/_localstack/health | grep -q available". It replaces the default
+// "Ready." line, lines set with WithReadyLogLines still have to be logged. The image needs a
+// shell, which LocalStack has
+func WithReadyScript(script string) StackOption {
+	return func(s *Stack) {
+		s.readyScript = script
+	}
+}
+
+// WithReadOnlyRootFS runs the container with a read-only root filesystem, as required by hardened
+// CI policies, and mounts tmpfs at the paths LocalStack writes to (/tmp/localstack and
+// /var/lib/localstack). It cannot be combined with PERSISTENCE=1, Start fails
+func WithReadOnlyRootFS() StackOption {
+	return func(s *Stack) {
+		s.readOnlyRootFS = true
+	}
+}
+
+// WithAutoRestart recreates the container up to maxRestarts times when it exits while the stack
+// is running, e.g. after a crash or an OOM kill, keeping its port when that is still free.
+// Restarts are logged, Restarts reports how many happened
+func WithAutoRestart(maxRestarts int) StackOption {
+	return func(s *Stack) {
+		s.maxRestarts = maxRestarts
+	}
+}
+
+// WithDefaultCredentialChain lets the SDK clients of the stack source their credentials from the
+// default provider chain (environment, shared config, ...) instead of the dummy static
+// credentials, e.g. to check that the code under test picks up the expected profile. WithAccountID
+// has no effect on the clients then
+func WithDefaultCredentialChain() StackOption {
+	return func(s *Stack) {
+		s.defaultCredentialChain = true
+	}
+}
+
+// WithPort additionally publishes containerPort, e.g. "4571" for the legacy Elasticsearch endpoint
+// or "53/udp", on hostPort, or on a port chosen by Docker when hostPort is empty. MappedPort
+// returns the resolved host port once started
+func WithPort(containerPort, hostPort string) (StackOption, error) {
+	port, err := parseContainerPort(containerPort)
+	if err != nil {
+		return nil, err
+	}
+	if hostPort != "" {
+		if _, err := nat.ParsePort(hostPort); err != nil {
+			return nil, fmt.Errorf("localstack: invalid host port %q", hostPort)
+		}
+	}
+	return func(s *Stack) {
+		if s.extraPorts == nil {
+			s.extraPorts = map[nat.Port]nat.PortBinding{}
+		}
+		s.extraPorts[port] = nat.PortBinding{HostPort: hostPort}
+	}, nil
+}
+
+// WithEnvFile sets the container environment from a file of KEY=VALUE lines, e.g. a .env file
+// shared by the tests. Blank lines and lines starting with # are skipped, a value may be quoted.
+// The file is read once, when the option is created, and later WithEnv calls override its values
+func WithEnvFile(path string) (StackOption, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("localstack: reading env file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	env, err := parseEnvFile(f)
+	if err != nil {
+		return nil, fmt.Errorf("localstack: env file %s: %w", path, err)
+	}
+	return func(s *Stack) {
+		if s.env == nil {
+			s.env = make(map[string]string)
+		}
+		for key, value := range env {
+			s.env[key] = value
+		}
+	}, nil
+}
+
+func parseEnvFile(r io.Reader) (map[string]string, error) {
+	env := map[string]string{}
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE, got %q", lineNumber, line)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		env[key] = value
+	}
+	return env, scanner.Err()
+}
+
+// WithSQSEndpointStrategy sets SQS_ENDPOINT_STRATEGY, which decides the host of the queue URLs
+// LocalStack returns: "standard" and "domain" use subdomains of localhost.localstack.cloud, "path"
+// and "off" the edge host itself. "path" is the most test-friendly, its queue URLs are reachable
+// from the host and need no DNS
+func WithSQSEndpointStrategy(strategy string) (StackOption, error) {
+	switch strategy {
+	case "standard", "domain", "path", "off":
+	default:
+		return nil, fmt.Errorf("localstack: unsupported SQS endpoint strategy %q", strategy)
+	}
+
+	return WithEnv("SQS_ENDPOINT_STRATEGY", strategy), nil
+}
@@ -0,0 +1,56 @@
+package localstack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	smtypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+// PutParameter creates or overwrites the String parameter name in SSM Parameter Store
+func (s *Stack) PutParameter(ctx context.Context, name, value string) error {
+	cfg, err := s.createTestConfig()
+	if err != nil {
+		return err
+	}
+	if _, err := ssm.NewFromConfig(cfg).PutParameter(ctx, &ssm.PutParameterInput{
+		Name:      aws.String(name),
+		Value:     aws.String(value),
+		Type:      ssmtypes.ParameterTypeString,
+		Overwrite: aws.Bool(true),
+	}); err != nil {
+		return fmt.Errorf("localstack: putting parameter %s: %w", name, err)
+	}
+	return nil
+}
+
+// CreateSecret creates the secret name in Secrets Manager, or stores value as its current version
+// if it already exists
+func (s *Stack) CreateSecret(ctx context.Context, name, value string) error {
+	cfg, err := s.createTestConfig()
+	if err != nil {
+		return err
+	}
+	api := secretsmanager.NewFromConfig(cfg)
+
+	_, err = api.CreateSecret(ctx, &secretsmanager.CreateSecretInput{
+		Name:         aws.String(name),
+		SecretString: aws.String(value),
+	})
+	var exists *smtypes.ResourceExistsException
+	if errors.As(err, &exists) {
+		_, err = api.PutSecretValue(ctx, &secretsmanager.PutSecretValueInput{
+			SecretId:     aws.String(name),
+			SecretString: aws.String(value),
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("localstack: creating secret %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package localstack
+
+import (
+	containertypes "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/go-connections/nat"
+)
+
+// ResolvedOptions is a snapshot of the configuration the stack creates its container with
+type ResolvedOptions struct {
+	Image         string
+	ContainerName string
+	Env           map[string]string
+	Mounts        []mount.Mount
+	PortBindings  nat.PortMap
+}
+
+// Options returns the effective configuration after all options were applied. Once started, the
+// port bindings are the ones resolved from the running container
+func (s *Stack) Options() ResolvedOptions {
+	s.RLock()
+	defer s.RUnlock()
+
+	portBindings := nat.PortMap{}
+	for port, bindings := range s.pm {
+		portBindings[port] = append([]nat.PortBinding(nil), bindings...)
+	}
+	if len(portBindings) == 0 {
+		portBindings[nat.Port(FixedPort)] = []nat.PortBinding{{HostIP: s.bindIP, HostPort: ""}}
+	}
+
+	env := copyMap(s.env)
+	if env == nil {
+		env = map[string]string{}
+	}
+
+	return ResolvedOptions{
+		Image:         s.image,
+		ContainerName: s.containerName,
+		Env:           env,
+		Mounts:        s.getVolumeMounts(),
+		PortBindings:  portBindings,
+	}
+}
+
+// PlanResult is what Start would create the container with, see Plan
+type PlanResult struct {
+	ResolvedOptions
+	// Platform is the platform the image is pulled and run for, empty for the daemon default
+	Platform string
+	// Config and HostConfig are passed to ContainerCreate, after all mutators ran
+	Config     *containertypes.Config
+	HostConfig *containertypes.HostConfig
+}
+
+// Plan applies opts to a copy of the stack and reports the container Start would create, without
+// touching Docker or the stack. A port chosen by WithAutoPort is only known at Start, so the host
+// port of the plan is empty
+func (s *Stack) Plan(opts ...StackOption) PlanResult {
+	c := s.Clone()
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.bindPorts("")
+
+	return PlanResult{
+		ResolvedOptions: c.Options(),
+		Platform:        c.platform,
+		Config:          c.containerConfig(),
+		HostConfig:      c.hostConfig(),
+	}
+}
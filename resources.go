@@ -0,0 +1,85 @@
+package localstack
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+// ListQueues returns the URLs of all SQS queues, rewritten to be reachable through EndpointURL
+func (s *Stack) ListQueues(ctx context.Context) ([]string, error) {
+	cfg, err := s.createTestConfig()
+	if err != nil {
+		return nil, err
+	}
+	paginator := sqs.NewListQueuesPaginator(sqs.NewFromConfig(cfg), &sqs.ListQueuesInput{})
+
+	var queues []string
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, queueURL := range page.QueueUrls {
+			queues = append(queues, s.rewriteURL(queueURL))
+		}
+	}
+	return queues, nil
+}
+
+// ListBuckets returns the names of all S3 buckets
+func (s *Stack) ListBuckets(ctx context.Context) ([]string, error) {
+	cfg, err := s.createTestConfig()
+	if err != nil {
+		return nil, err
+	}
+	out, err := s3.NewFromConfig(cfg, func(o *s3.Options) { o.UsePathStyle = true }).ListBuckets(ctx, &s3.ListBucketsInput{})
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := make([]string, 0, len(out.Buckets))
+	for _, bucket := range out.Buckets {
+		buckets = append(buckets, aws.ToString(bucket.Name))
+	}
+	return buckets, nil
+}
+
+// ListTables returns the names of all DynamoDB tables
+func (s *Stack) ListTables(ctx context.Context) ([]string, error) {
+	cfg, err := s.createTestConfig()
+	if err != nil {
+		return nil, err
+	}
+	paginator := dynamodb.NewListTablesPaginator(dynamodb.NewFromConfig(cfg), &dynamodb.ListTablesInput{})
+
+	var tables []string
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, page.TableNames...)
+	}
+	return tables, nil
+}
+
+// rewriteURL points a URL returned by LocalStack (e.g. a queue URL using the container hostname)
+// at the host and port of EndpointURL, keeping its path
+func (s *Stack) rewriteURL(rawURL string) string {
+	endpoint, err := url.Parse(s.EndpointURL())
+	if err != nil || endpoint.Host == "" {
+		return rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	u.Scheme = endpoint.Scheme
+	u.Host = endpoint.Host
+	return u.String()
+}
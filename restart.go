@@ -0,0 +1,119 @@
+package localstack
+
+import (
+	"context"
+	"time"
+
+	containertypes "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+// Restarts returns how often the container was recreated after exiting unexpectedly, see
+// WithAutoRestart
+func (s *Stack) Restarts() int {
+	s.RLock()
+	defer s.RUnlock()
+	return s.restarts
+}
+
+// supervise waits for the container to exit and recreates it when the stack did not stop it
+func (s *Stack) supervise(containerID string) {
+	exitCode, ok := s.waitExited(containerID)
+	if !ok {
+		return
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	// Stop holds the lock until the container is gone, so an intended exit is seen as a new state
+	if !s.started || s.containerID != containerID {
+		return
+	}
+	if s.restarts >= s.maxRestarts {
+		s.log().Error("container exited unexpectedly, restart limit reached", "container", containerID, "exitCode", exitCode, "restarts", s.restarts)
+		s.markExited()
+		return
+	}
+	s.restarts++
+	s.log().Warn("container exited unexpectedly, restarting", "container", containerID, "exitCode", exitCode, "restart", s.restarts)
+
+	s.removeExited(containerID)
+	s.markExited()
+	s.restarting = true
+	err := s.start()
+	s.restarting = false
+	if err != nil {
+		s.log().Error("could not restart container", "error", err)
+		return
+	}
+	s.log().Info("container restarted", "container", s.containerID, "restart", s.restarts)
+}
+
+// waitExited blocks until the container exited and returns its exit code. A failed wait, e.g. a
+// dropped daemon connection, is re-armed unless the container is really gone. It reports false
+// once the stack no longer runs the container
+func (s *Stack) waitExited(containerID string) (int64, bool) {
+	b := newBackoff(time.Second)
+	for {
+		exited, waitErr := s.cli.ContainerWait(context.Background(), containerID, containertypes.WaitConditionNotRunning)
+		select {
+		case resp := <-exited:
+			return resp.StatusCode, true
+		case <-waitErr:
+		}
+
+		s.RLock()
+		current := s.started && s.containerID == containerID
+		s.RUnlock()
+		if !current {
+			return 0, false
+		}
+		if exitCode, exited := s.containerExited(containerID); exited {
+			return exitCode, true
+		}
+		_ = b.wait(context.Background())
+	}
+}
+
+// containerExited inspects the container, which counts as exited when it stopped running or is
+// gone. An inspection that fails otherwise tells nothing, the container is assumed running
+func (s *Stack) containerExited(containerID string) (int64, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+	defer cancel()
+	cont, err := s.cli.ContainerInspect(ctx, containerID)
+	if client.IsErrNotFound(err) {
+		return -1, true
+	}
+	if err != nil || cont.State == nil {
+		return 0, false
+	}
+	if cont.State.Running {
+		return 0, false
+	}
+	return int64(cont.State.ExitCode), true
+}
+
+// removeExited makes sure an exited container is gone, so that its name can be taken again
+func (s *Stack) removeExited(containerID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+	defer cancel()
+	removed, waitErr := s.cli.ContainerWait(ctx, containerID, containertypes.WaitConditionRemoved)
+	_ = s.cli.ContainerRemove(ctx, containerID, containertypes.RemoveOptions{Force: true})
+	select {
+	case <-removed:
+	case <-waitErr:
+	}
+}
+
+// markExited records that the container is gone without stopping it, the port mapping is kept so
+// a restart can reuse it
+func (s *Stack) markExited() {
+	s.containerID = ""
+	s.started = false
+	s.publishEndpoint()
+	s.resetConfig()
+	if s.stopped != nil {
+		close(s.stopped)
+		s.stopped = nil
+	}
+}
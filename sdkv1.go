@@ -0,0 +1,28 @@
+package localstack
+
+// V1Endpoint returns the endpoint for clients of aws-sdk-go v1, which takes one URL for all
+// services. Together with Region and StaticCredentials it configures a v1 session without the
+// package depending on the v1 SDK:
+//
+//	sess := session.Must(session.NewSession(&aws.Config{
+//		Endpoint:         aws.String(stack.V1Endpoint()),
+//		Region:           aws.String(stack.Region()),
+//		Credentials:      credentials.NewStaticCredentials(stack.StaticCredentials()),
+//		S3ForcePathStyle: aws.Bool(true),
+//	}))
+//
+// With WithTLS the session additionally needs an HTTPClient that skips certificate verification
+func (s *Stack) V1Endpoint() string {
+	return s.EndpointURL()
+}
+
+// Region returns the region the clients of the stack use, see WithRegion
+func (s *Stack) Region() string {
+	return s.region
+}
+
+// StaticCredentials returns the access key id, secret and session token the clients of the stack
+// sign with. LocalStack does not verify them, the access key id only selects the account
+func (s *Stack) StaticCredentials() (accessKeyID, secretAccessKey, sessionToken string) {
+	return s.accessKeyID(), "dummy", "dummy"
+}
@@ -0,0 +1,141 @@
+package localstack
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+var (
+	sharedMu     sync.Mutex
+	sharedStacks = map[string]*sharedStack{}
+)
+
+// sharedStack is a registry entry of StartShared
+type sharedStack struct {
+	stack *Stack
+	refs  int
+}
+
+// StartShared starts a stack for use from TestMain, or returns the running one if the process
+// already started a stack with the same resolved options. The returned release function stops
+// the stack once every caller released it. It panics if the stack cannot be started.
+//
+// Go runs the tests of each package in its own process, so the registry only dedupes within one
+// process. Packages that run in parallel processes can additionally pass WithReuseExisting to
+// attach to the container another process created; note that the stop on release still removes
+// it for everyone, so run such packages with -p 1 or keep the container running externally.
+//
+//	var stack *localstack.Stack
+//
+//	func TestMain(m *testing.M) {
+//		var release func()
+//		stack, release = localstack.StartShared(localstack.WithAutoPort())
+//		code := m.Run()
+//		release()
+//		os.Exit(code)
+//	}
+func StartShared(opts ...StackOption) (*Stack, func()) {
+	stack := New()
+	for _, opt := range opts {
+		opt(stack)
+	}
+	key, err := stackSignature(stack)
+	if err != nil {
+		panic(err)
+	}
+
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+
+	entry, ok := sharedStacks[key]
+	if !ok {
+		if err := stack.Start(false); err != nil {
+			panic(err)
+		}
+		entry = &sharedStack{stack: stack}
+		sharedStacks[key] = entry
+	}
+	entry.refs++
+
+	var once sync.Once
+	return entry.stack, func() {
+		once.Do(func() { releaseShared(key) })
+	}
+}
+
+// releaseShared drops a reference and stops the stack when it was the last one
+func releaseShared(key string) {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+
+	entry, ok := sharedStacks[key]
+	if !ok {
+		return
+	}
+	if entry.refs--; entry.refs > 0 {
+		return
+	}
+	delete(sharedStacks, key)
+	if err := entry.stack.Stop(); err != nil {
+		entry.stack.log().Error("could not stop shared stack", "error", err)
+	}
+}
+
+// signature is everything a StartShared caller can observe of a stack: the container it creates,
+// how readiness is decided and how its clients are configured. All of it marshals deterministically
+type signature struct {
+	Plan                   PlanResult
+	ReuseExisting          bool
+	ReplaceExisting        bool
+	WaitForInit            bool
+	InitCompleteLogLine    string
+	ReadyLogLines          []string
+	ReadyFile              string
+	ReadyScript            string
+	AttachedEndpoint       string
+	EndpointHost           string
+	UseTLS                 bool
+	Region                 string
+	Partition              string
+	AccountID              string
+	DefaultCredentialChain bool
+	UseBaseEndpoint        bool
+	RetryMode              aws.RetryMode
+	RetryMaxAttempts       int
+}
+
+// stackSignature identifies the container a stack would create and the clients it hands out
+func stackSignature(s *Stack) (string, error) {
+	plan := s.Plan()
+	s.RLock()
+	sig := signature{
+		Plan:                   plan,
+		ReuseExisting:          s.reuseExisting,
+		ReplaceExisting:        s.replaceExisting,
+		WaitForInit:            s.waitForInit,
+		InitCompleteLogLine:    s.initCompleteLogLine,
+		ReadyLogLines:          s.readyLogLines,
+		ReadyFile:              s.readyFile,
+		ReadyScript:            s.readyScript,
+		AttachedEndpoint:       s.attachedEndpoint,
+		EndpointHost:           s.endpointHost,
+		UseTLS:                 s.useTLS,
+		Region:                 s.region,
+		Partition:              s.partition,
+		AccountID:              s.accountID,
+		DefaultCredentialChain: s.defaultCredentialChain,
+		UseBaseEndpoint:        s.useBaseEndpoint,
+		RetryMode:              s.retryMode,
+		RetryMaxAttempts:       s.retryMaxAttempts,
+	}
+	s.RUnlock()
+
+	raw, err := json.Marshal(sig)
+	if err != nil {
+		return "", fmt.Errorf("localstack: computing stack signature: %w", err)
+	}
+	return string(raw), nil
+}
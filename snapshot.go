@@ -0,0 +1,118 @@
+package localstack
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+// maxObjectSize bounds the objects GetObjectString reads into memory
+const maxObjectSize = 10 << 20
+
+// DumpTable scans every item of a DynamoDB table, e.g. to compare it against a golden file
+func (s *Stack) DumpTable(ctx context.Context, table string) ([]map[string]types.AttributeValue, error) {
+	cfg, err := s.createTestConfig()
+	if err != nil {
+		return nil, err
+	}
+	paginator := dynamodb.NewScanPaginator(dynamodb.NewFromConfig(cfg), &dynamodb.ScanInput{
+		TableName:      aws.String(table),
+		ConsistentRead: aws.Bool(true),
+	})
+
+	var items []map[string]types.AttributeValue
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page.Items...)
+	}
+	return items, nil
+}
+
+// DumpQueue returns the bodies of the messages currently visible in an SQS queue. Received
+// messages are made visible again before it returns, so they stay available to the code under
+// test
+func (s *Stack) DumpQueue(ctx context.Context, queueURL string) ([]string, error) {
+	cfg, err := s.createTestConfig()
+	if err != nil {
+		return nil, err
+	}
+	api := sqs.NewFromConfig(cfg)
+
+	// while dumping, received messages stay hidden so that every receive returns new ones
+	var receiptHandles []*string
+	defer func() {
+		resetCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer cancel()
+		for _, handle := range receiptHandles {
+			// the batch API drops a zero timeout from the request, the single one always sends it
+			_, _ = api.ChangeMessageVisibility(resetCtx, &sqs.ChangeMessageVisibilityInput{
+				QueueUrl:          aws.String(queueURL),
+				ReceiptHandle:     handle,
+				VisibilityTimeout: 0,
+			})
+		}
+	}()
+
+	seen := make(map[string]bool)
+	var bodies []string
+	for {
+		out, err := api.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(queueURL),
+			MaxNumberOfMessages: 10,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(out.Messages) == 0 {
+			return bodies, nil
+		}
+
+		for _, msg := range out.Messages {
+			receiptHandles = append(receiptHandles, msg.ReceiptHandle)
+			if seen[aws.ToString(msg.MessageId)] {
+				continue
+			}
+			seen[aws.ToString(msg.MessageId)] = true
+			bodies = append(bodies, aws.ToString(msg.Body))
+		}
+	}
+}
+
+// GetObjectString returns the content of an S3 object and whether it exists; a missing bucket or
+// key is reported as not found rather than an error. It is meant for small fixtures and fails for
+// objects above 10 MiB
+func (s *Stack) GetObjectString(ctx context.Context, bucket, key string) (string, bool, error) {
+	cfg, err := s.createTestConfig()
+	if err != nil {
+		return "", false, err
+	}
+	api := s3.NewFromConfig(cfg, func(o *s3.Options) { o.UsePathStyle = true })
+
+	out, err := api.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+	if err != nil {
+		if isNotFound(err) {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("localstack: getting %s/%s: %w", bucket, key, err)
+	}
+	defer func() { _ = out.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(out.Body, maxObjectSize+1))
+	if err != nil {
+		return "", false, fmt.Errorf("localstack: reading %s/%s: %w", bucket, key, err)
+	}
+	if len(body) > maxObjectSize {
+		return "", true, fmt.Errorf("localstack: %s/%s is larger than %d bytes", bucket, key, maxObjectSize)
+	}
+	return string(body), true, nil
+}
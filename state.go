@@ -0,0 +1,110 @@
+package localstack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// stateRequestTimeout bounds a state operation when ctx has no earlier deadline; large states can
+// take a while to serialize
+const stateRequestTimeout = 2 * time.Minute
+
+// Reset discards the state of all services
+func (s *Stack) Reset(ctx context.Context) error {
+	resp, err := s.stateRequest(ctx, "reset state", http.MethodPost, "/_localstack/state/reset", nil)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// SaveState writes a snapshot of the state of all services to w, to be restored with LoadState
+func (s *Stack) SaveState(ctx context.Context, w io.Writer) error {
+	resp, err := s.stateRequest(ctx, "save state", http.MethodGet, "/_localstack/pods/state", nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("localstack: save state: %w", err)
+	}
+	return nil
+}
+
+// LoadState restores a snapshot written by SaveState
+func (s *Stack) LoadState(ctx context.Context, r io.Reader) error {
+	resp, err := s.stateRequest(ctx, "load state", http.MethodPost, "/_localstack/pods", r)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// stateRequest sends a request to a state endpoint and returns the response if it succeeded. On
+// failure the error carries the status and the message of LocalStack's error payload
+func (s *Stack) stateRequest(ctx context.Context, op, method, path string, body io.Reader) (*http.Response, error) {
+	if !s.started {
+		return nil, ErrNotStarted
+	}
+	ctx, cancel := context.WithTimeout(ctx, stateRequestTimeout)
+	req, err := http.NewRequestWithContext(ctx, method, s.EndpointURL()+path, body)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("localstack: %s: %w", op, err)
+	}
+
+	resp, err := s.httpClient().Do(req)
+	if err != nil {
+		cancel()
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) {
+			return nil, fmt.Errorf("localstack: %s: timed out: %w", op, err)
+		}
+		return nil, fmt.Errorf("localstack: %s: %w", op, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer cancel()
+		defer func() { _ = resp.Body.Close() }()
+		return nil, fmt.Errorf("localstack: %s: %s%s", op, resp.Status, errorMessage(resp.Body))
+	}
+	resp.Body = cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// errorMessage extracts the message of a LocalStack error payload, formatted for appending
+func errorMessage(r io.Reader) string {
+	raw, _ := io.ReadAll(io.LimitReader(r, 4096))
+	var payload struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(raw, &payload); err == nil {
+		if payload.Message != "" {
+			return ": " + payload.Message
+		}
+		if payload.Error != "" {
+			return ": " + payload.Error
+		}
+	}
+	if text := strings.TrimSpace(string(raw)); text != "" {
+		return ": " + text
+	}
+	return ""
+}
+
+// cancelOnClose releases the request context once the response body is consumed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
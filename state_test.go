@@ -0,0 +1,26 @@
+package localstack
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ResetReportsErrorPayload(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/_localstack/state/reset", r.URL.Path)
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"error": "InternalError", "message": "state is locked"}`))
+	}))
+	defer server.Close()
+
+	err := Attach(server.URL).Reset(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, "localstack: reset state: 500 Internal Server Error: state is locked", err.Error())
+}
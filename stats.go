@@ -0,0 +1,71 @@
+package localstack
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/docker/docker/api/types"
+)
+
+// ContainerStats is a snapshot of the resource usage of the container
+type ContainerStats struct {
+	// CPUPercent is the usage since the previous sample, 100 per fully used core
+	CPUPercent float64
+	// MemoryUsage is the memory in use in bytes, excluding the page cache
+	MemoryUsage uint64
+	// MemoryLimit is the memory available to the container in bytes
+	MemoryLimit uint64
+	// MemoryPercent is MemoryUsage relative to MemoryLimit
+	MemoryPercent float64
+}
+
+// Stats samples the CPU and memory usage of the running container. The daemon collects two
+// samples to compute the CPU usage, so the call takes about a second
+func (s *Stack) Stats(ctx context.Context) (ContainerStats, error) {
+	s.RLock()
+	defer s.RUnlock()
+	if !s.started || s.containerID == "" {
+		return ContainerStats{}, ErrNotStarted
+	}
+
+	resp, err := s.cli.ContainerStats(ctx, s.containerID, false)
+	if err != nil {
+		return ContainerStats{}, fmt.Errorf("localstack: reading stats: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var raw types.StatsJSON
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return ContainerStats{}, fmt.Errorf("localstack: reading stats: %w", err)
+	}
+	return parseStats(raw), nil
+}
+
+// parseStats computes usage the way the docker CLI does for Linux containers
+func parseStats(raw types.StatsJSON) ContainerStats {
+	var stats ContainerStats
+
+	cpuDelta := float64(raw.CPUStats.CPUUsage.TotalUsage) - float64(raw.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(raw.CPUStats.SystemUsage) - float64(raw.PreCPUStats.SystemUsage)
+	cpus := float64(raw.CPUStats.OnlineCPUs)
+	if cpus == 0 {
+		cpus = float64(len(raw.CPUStats.CPUUsage.PercpuUsage))
+	}
+	if cpuDelta > 0 && systemDelta > 0 {
+		stats.CPUPercent = cpuDelta / systemDelta * cpus * 100
+	}
+
+	stats.MemoryUsage = raw.MemoryStats.Usage
+	// cgroup v1 reports the page cache as total_inactive_file, cgroup v2 as inactive_file
+	if cache, ok := raw.MemoryStats.Stats["total_inactive_file"]; ok && cache < stats.MemoryUsage {
+		stats.MemoryUsage -= cache
+	} else if cache, ok := raw.MemoryStats.Stats["inactive_file"]; ok && cache < stats.MemoryUsage {
+		stats.MemoryUsage -= cache
+	}
+	stats.MemoryLimit = raw.MemoryStats.Limit
+	if stats.MemoryLimit > 0 {
+		stats.MemoryPercent = float64(stats.MemoryUsage) / float64(stats.MemoryLimit) * 100
+	}
+	return stats
+}
@@ -0,0 +1,159 @@
+package localstack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/smithy-go"
+)
+
+// TagQueue adds tags to a queue, overwriting tags with the same keys
+func (s *Stack) TagQueue(ctx context.Context, url string, tags map[string]string) error {
+	cfg, err := s.createTestConfig()
+	if err != nil {
+		return err
+	}
+	if _, err := sqs.NewFromConfig(cfg).TagQueue(ctx, &sqs.TagQueueInput{QueueUrl: aws.String(url), Tags: tags}); err != nil {
+		return fmt.Errorf("localstack: tagging queue %s: %w", url, err)
+	}
+	return nil
+}
+
+// QueueTags returns the tags of a queue
+func (s *Stack) QueueTags(ctx context.Context, url string) (map[string]string, error) {
+	cfg, err := s.createTestConfig()
+	if err != nil {
+		return nil, err
+	}
+	out, err := sqs.NewFromConfig(cfg).ListQueueTags(ctx, &sqs.ListQueueTagsInput{QueueUrl: aws.String(url)})
+	if err != nil {
+		return nil, fmt.Errorf("localstack: listing tags of queue %s: %w", url, err)
+	}
+	return nonNilTags(out.Tags), nil
+}
+
+// TagBucket adds tags to a bucket, overwriting tags with the same keys. S3 only replaces the whole
+// tag set, so the current tags are merged in
+func (s *Stack) TagBucket(ctx context.Context, bucket string, tags map[string]string) error {
+	current, err := s.BucketTags(ctx, bucket)
+	if err != nil {
+		return err
+	}
+	for key, value := range tags {
+		current[key] = value
+	}
+
+	keys := make([]string, 0, len(current))
+	for key := range current {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	tagSet := make([]s3types.Tag, 0, len(keys))
+	for _, key := range keys {
+		tagSet = append(tagSet, s3types.Tag{Key: aws.String(key), Value: aws.String(current[key])})
+	}
+
+	cfg, err := s.createTestConfig()
+	if err != nil {
+		return err
+	}
+	api := s3.NewFromConfig(cfg, func(o *s3.Options) { o.UsePathStyle = true })
+	if _, err := api.PutBucketTagging(ctx, &s3.PutBucketTaggingInput{
+		Bucket:  aws.String(bucket),
+		Tagging: &s3types.Tagging{TagSet: tagSet},
+	}); err != nil {
+		return fmt.Errorf("localstack: tagging bucket %s: %w", bucket, err)
+	}
+	return nil
+}
+
+// BucketTags returns the tags of a bucket, a bucket without tags has an empty map
+func (s *Stack) BucketTags(ctx context.Context, bucket string) (map[string]string, error) {
+	cfg, err := s.createTestConfig()
+	if err != nil {
+		return nil, err
+	}
+	api := s3.NewFromConfig(cfg, func(o *s3.Options) { o.UsePathStyle = true })
+
+	out, err := api.GetBucketTagging(ctx, &s3.GetBucketTaggingInput{Bucket: aws.String(bucket)})
+	if err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "NoSuchTagSet" {
+			return map[string]string{}, nil
+		}
+		return nil, fmt.Errorf("localstack: getting tags of bucket %s: %w", bucket, err)
+	}
+	tags := make(map[string]string, len(out.TagSet))
+	for _, tag := range out.TagSet {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return tags, nil
+}
+
+// TagTable adds tags to a DynamoDB table, overwriting tags with the same keys
+func (s *Stack) TagTable(ctx context.Context, table string, tags map[string]string) error {
+	api, arn, err := s.tableARN(ctx, table)
+	if err != nil {
+		return err
+	}
+	var tagList []dynamodbtypes.Tag
+	for key, value := range tags {
+		tagList = append(tagList, dynamodbtypes.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+	if _, err := api.TagResource(ctx, &dynamodb.TagResourceInput{ResourceArn: aws.String(arn), Tags: tagList}); err != nil {
+		return fmt.Errorf("localstack: tagging table %s: %w", table, err)
+	}
+	return nil
+}
+
+// TableTags returns the tags of a DynamoDB table
+func (s *Stack) TableTags(ctx context.Context, table string) (map[string]string, error) {
+	api, arn, err := s.tableARN(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	tags := map[string]string{}
+	input := &dynamodb.ListTagsOfResourceInput{ResourceArn: aws.String(arn)}
+	for {
+		out, err := api.ListTagsOfResource(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("localstack: listing tags of table %s: %w", table, err)
+		}
+		for _, tag := range out.Tags {
+			tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+		}
+		if out.NextToken == nil {
+			return tags, nil
+		}
+		input.NextToken = out.NextToken
+	}
+}
+
+// tableARN resolves the ARN the DynamoDB tagging APIs address a table by
+func (s *Stack) tableARN(ctx context.Context, table string) (*dynamodb.Client, string, error) {
+	cfg, err := s.createTestConfig()
+	if err != nil {
+		return nil, "", err
+	}
+	api := dynamodb.NewFromConfig(cfg)
+	out, err := api.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(table)})
+	if err != nil {
+		return nil, "", fmt.Errorf("localstack: describing table %s: %w", table, err)
+	}
+	return api, aws.ToString(out.Table.TableArn), nil
+}
+
+func nonNilTags(tags map[string]string) map[string]string {
+	if tags == nil {
+		return map[string]string{}
+	}
+	return tags
+}
@@ -0,0 +1,134 @@
+package localstack
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// WaitForQueueDepth polls a queue until it holds at least atLeast messages or ctx is done. The
+// count is SQS's ApproximateNumberOfMessages, which lags slightly behind sends and does not
+// include in-flight messages, so give ctx some headroom
+func (s *Stack) WaitForQueueDepth(ctx context.Context, queueURL string, atLeast int) error {
+	cfg, err := s.createTestConfig()
+	if err != nil {
+		return err
+	}
+	api := sqs.NewFromConfig(cfg)
+
+	depth := 0
+	return poll(ctx, func() (bool, error) {
+		out, err := api.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+			QueueUrl:       aws.String(queueURL),
+			AttributeNames: []sqstypes.QueueAttributeName{sqstypes.QueueAttributeNameApproximateNumberOfMessages},
+		})
+		if err != nil {
+			return false, fmt.Errorf("localstack: reading depth of %s: %w", queueURL, err)
+		}
+		depth, _ = strconv.Atoi(out.Attributes[string(sqstypes.QueueAttributeNameApproximateNumberOfMessages)])
+		return depth >= atLeast, nil
+	}, func(err error) error {
+		return fmt.Errorf("localstack: queue %s has %d of %d messages: %w", queueURL, depth, atLeast, err)
+	})
+}
+
+// WaitForLogEvents polls the CloudWatch log group logGroup, e.g. "/aws/lambda/<function>", until an
+// event containing match appears or ctx is done. A group that does not exist yet is waited for.
+// Lambda logs are ingested asynchronously and show up a moment after the invocation returned
+func (s *Stack) WaitForLogEvents(ctx context.Context, logGroup string, match string) error {
+	cfg, err := s.createTestConfig()
+	if err != nil {
+		return err
+	}
+	api := cloudwatchlogs.NewFromConfig(cfg)
+
+	var since int64
+	return poll(ctx, func() (bool, error) {
+		paginator := cloudwatchlogs.NewFilterLogEventsPaginator(api, &cloudwatchlogs.FilterLogEventsInput{
+			LogGroupName: aws.String(logGroup),
+			StartTime:    aws.Int64(since),
+		})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				if isNotFound(err) {
+					return false, nil
+				}
+				return false, fmt.Errorf("localstack: reading log group %s: %w", logGroup, err)
+			}
+			for _, event := range page.Events {
+				if strings.Contains(aws.ToString(event.Message), match) {
+					return true, nil
+				}
+				if ts := aws.ToInt64(event.Timestamp); ts > since {
+					since = ts
+				}
+			}
+		}
+		return false, nil
+	}, func(err error) error {
+		return fmt.Errorf("localstack: no event containing %q in log group %s: %w", match, logGroup, err)
+	})
+}
+
+// maxPollInterval caps the backoff of all polling loops unless their initial interval is larger
+const maxPollInterval = 2 * time.Second
+
+// poll calls check with backoff until it reports done, fails, or ctx is done, in which case the
+// context error is passed through onTimeout
+func poll(ctx context.Context, check func() (bool, error), onTimeout func(error) error) error {
+	b := newBackoff(100 * time.Millisecond)
+	for {
+		done, err := check()
+		if err != nil || done {
+			return err
+		}
+		if err := b.wait(ctx); err != nil {
+			return onTimeout(err)
+		}
+	}
+}
+
+// backoff spaces out the iterations of a polling loop exponentially up to maxPollInterval. Every
+// wait is jittered, so many stacks polling in parallel do not hit Docker and LocalStack in sync
+type backoff struct {
+	interval time.Duration
+	max      time.Duration
+}
+
+func newBackoff(initial time.Duration) *backoff {
+	if initial <= 0 {
+		initial = 100 * time.Millisecond
+	}
+	max := maxPollInterval
+	if initial > max {
+		max = initial
+	}
+	return &backoff{interval: initial, max: max}
+}
+
+// wait sleeps for the current interval, randomized to between half and all of it, and grows the
+// interval. It returns early with the context error once ctx is done
+func (b *backoff) wait(ctx context.Context) error {
+	d := b.interval/2 + time.Duration(rand.Int63n(int64(b.interval/2)+1))
+	if b.interval *= 2; b.interval > b.max {
+		b.interval = b.max
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}